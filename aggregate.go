@@ -0,0 +1,444 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// default window and number of groups of the aggregate hook
+	defaultAggregateWindow = 10 * time.Second
+	defaultAggregateGroups = 4096
+)
+
+// aggregateHook is a Logrus hook that turns groups of messages into
+// periodic summaries
+type aggregateHook struct {
+	sync.Mutex
+
+	ChainImpl
+	conf aggregateParams
+
+	// groups holds the aggregates of the current window, in the order in
+	// which the groups were first seen
+	groups *keyCache[*aggregate]
+	start  time.Time
+
+	running   bool
+	stop      chan struct{}
+	done      sync.WaitGroup
+	errLogger *log.Logger
+}
+
+// aggregateParams defines the groups and the window of the hook
+type aggregateParams struct {
+	window    time.Duration
+	fields    []string
+	numeric   []string
+	maxGroups int
+	clock     Clock
+}
+
+// aggregate is the summary of the messages of a group
+type aggregate struct {
+	entry       *logrus.Entry
+	level       logrus.Level
+	count       int
+	first, last time.Time
+	numbers     map[string]*numberAggregate
+}
+
+// numberAggregate is the summary of the values of a numeric field
+type numberAggregate struct {
+	min, max, sum float64
+}
+
+// constructor --------------------------------------------------------
+
+// AggregateOption is a functional option to update the aggregate hook configuration
+type AggregateOption func(conf *aggregateParams)
+
+// AggregateWindow sets the time over which the messages are aggregated
+func AggregateWindow(d time.Duration) AggregateOption {
+	return func(conf *aggregateParams) {
+		if d > 0 {
+			conf.window = d
+		}
+	}
+}
+
+// AggregateFields adds fields to the key of the groups, messages with
+// different values of these fields are aggregated separately
+func AggregateFields(names ...string) AggregateOption {
+	return func(conf *aggregateParams) {
+		conf.fields = append(conf.fields, names...)
+	}
+}
+
+// AggregateNumeric selects the numeric fields that are aggregated, by
+// default all numeric fields are
+func AggregateNumeric(names ...string) AggregateOption {
+	return func(conf *aggregateParams) {
+		conf.numeric = append(conf.numeric, names...)
+	}
+}
+
+// AggregateMaxGroups sets the maximum number of groups in a window, the
+// oldest groups are reported early when the limit is reached
+func AggregateMaxGroups(n int) AggregateOption {
+	return func(conf *aggregateParams) {
+		if n > 0 {
+			conf.maxGroups = n
+		}
+	}
+}
+
+// AggregateClock sets the clock that drives the windows of the hook
+func AggregateClock(c Clock) AggregateOption {
+	return func(conf *aggregateParams) {
+		conf.clock = c
+	}
+}
+
+// AggregateHook creates a Logrus hook that turns groups of messages into
+// periodic summaries
+//
+// The messages with the same text and the same values of the selected
+// fields form a group. At the end of every window one message per group is
+// sent to the next hook, with the count of the messages, the times of the
+// first and the last one, and the min, max and sum of the numeric fields,
+// like "latency_min". The summary has the most severe level of the group.
+//
+// The windows end when a message arrives after the window is over, and on
+// the timer of the clock while the hook is running.
+func AggregateHook(next logrus.Hook, opts ...AggregateOption) RunningHook {
+
+	hook := &aggregateHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: aggregateParams{
+			window:    defaultAggregateWindow,
+			maxGroups: defaultAggregateGroups,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	hook.conf.clock = clockOrSystem(hook.conf.clock)
+	hook.errLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+	// groups are reported by the hook, the cache only keeps them in order
+	hook.groups = newKeyCache[*aggregate](0, 0)
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire adds the message to the aggregate of its group
+func (h *aggregateHook) Fire(entry *logrus.Entry) error {
+	now := h.conf.clock.Now()
+
+	h.Lock()
+	var closed []*aggregate
+	if h.start.IsZero() {
+		h.start = now
+	} else if now.Sub(h.start) >= h.conf.window {
+		closed = h.closeWindow(now)
+	}
+
+	if entry != nil {
+		key := h.groupKey(entry)
+		agg, found := h.groups.peek(key)
+		if !found {
+			agg = &aggregate{
+				entry:   copyEntry(entry),
+				level:   entry.Level,
+				numbers: make(map[string]*numberAggregate),
+			}
+			h.groups.add(key, agg, now)
+			closed = append(closed, h.evictOverflow()...)
+		}
+		h.add(agg, entry, now)
+	}
+	h.Unlock()
+
+	return h.report(closed, now)
+}
+
+// IsRunning queries the state of the hook
+func (h *aggregateHook) IsRunning() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.running
+}
+
+// Start launches the timer that ends the windows
+func (h *aggregateHook) Start() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.running {
+		return nil
+	}
+
+	h.stop = make(chan struct{})
+	h.done.Add(1)
+	go h.ticker(h.conf.clock.NewTimer(h.untilClose(h.conf.clock.Now())), h.stop)
+
+	h.running = true
+
+	return nil
+}
+
+// Stop stops the timer and reports the aggregates of the current window
+func (h *aggregateHook) Stop() error {
+	h.Lock()
+	if h.running {
+		close(h.stop)
+		h.running = false
+	}
+	h.Unlock()
+
+	h.done.Wait()
+
+	return h.Flush()
+}
+
+// Flush reports the aggregates of the current window and starts a new one
+func (h *aggregateHook) Flush() error {
+	now := h.conf.clock.Now()
+
+	h.Lock()
+	closed := h.closeWindow(now)
+	h.Unlock()
+
+	return h.report(closed, now)
+}
+
+// Inspect describes the configuration of the hook and the number of groups
+func (h *aggregateHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	return map[string]interface{}{
+		"window":     h.conf.window.String(),
+		"fields":     append([]string{}, h.conf.fields...),
+		"max_groups": h.conf.maxGroups,
+		"groups":     h.groups.len(),
+		"running":    h.running,
+	}
+}
+
+// ticker ends the windows on the timer until the hook is stopped
+func (h *aggregateHook) ticker(timer Timer, stop chan struct{}) {
+	defer h.done.Done()
+
+	for {
+		select {
+		case <-timer.C():
+			if err := h.closeExpired(); err != nil {
+				h.errLogger.Printf("aggregate logrus hook: %s", err)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		// the window may have been ended by a message in the meantime
+		h.Lock()
+		d := h.untilClose(h.conf.clock.Now())
+		h.Unlock()
+		timer.Reset(d)
+	}
+}
+
+// closeExpired ends the window if it is over and reports its aggregates
+func (h *aggregateHook) closeExpired() error {
+	now := h.conf.clock.Now()
+
+	h.Lock()
+	var closed []*aggregate
+	if h.start.IsZero() || now.Sub(h.start) >= h.conf.window {
+		closed = h.closeWindow(now)
+	}
+	h.Unlock()
+
+	return h.report(closed, now)
+}
+
+// untilClose is the time until the current window ends, or the length of a
+// window when no window was started yet
+// note: this function must be called with the hook's mutex locked
+func (h *aggregateHook) untilClose(now time.Time) time.Duration {
+	if h.start.IsZero() {
+		return h.conf.window
+	}
+
+	return max(0, h.start.Add(h.conf.window).Sub(now))
+}
+
+// groupKey identifies the group of a message
+func (h *aggregateHook) groupKey(entry *logrus.Entry) string {
+	var key strings.Builder
+	key.WriteString(entry.Message)
+	for _, name := range h.conf.fields {
+		if value, found := entry.Data[name]; found {
+			fmt.Fprintf(&key, "|%s=%v", name, value)
+		}
+	}
+
+	return key.String()
+}
+
+// add counts the message in the aggregate of its group
+// note: this function must be called with the hook's mutex locked
+func (h *aggregateHook) add(agg *aggregate, entry *logrus.Entry, now time.Time) {
+	at := entry.Time
+	if at.IsZero() {
+		at = now
+	}
+	if agg.count == 0 || at.Before(agg.first) {
+		agg.first = at
+	}
+	if agg.count == 0 || at.After(agg.last) {
+		agg.last = at
+	}
+	if entry.Level < agg.level {
+		agg.level = entry.Level
+	}
+	agg.count++
+
+	for k, v := range entry.Data {
+		if !h.isNumeric(k) {
+			continue
+		}
+		n, ok := toFloat(v)
+		if !ok {
+			continue
+		}
+
+		num, found := agg.numbers[k]
+		if !found {
+			agg.numbers[k] = &numberAggregate{min: n, max: n, sum: n}
+			continue
+		}
+		num.min = min(num.min, n)
+		num.max = max(num.max, n)
+		num.sum += n
+	}
+}
+
+// isNumeric checks if the field is aggregated as a number
+func (h *aggregateHook) isNumeric(name string) bool {
+	for _, field := range h.conf.fields {
+		if field == name {
+			// the fields of the key have the same value in the whole group
+			return false
+		}
+	}
+	if len(h.conf.numeric) == 0 {
+		return true
+	}
+	for _, field := range h.conf.numeric {
+		if field == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closeWindow removes the aggregates of the window and starts a new one
+// note: this function must be called with the hook's mutex locked
+func (h *aggregateHook) closeWindow(now time.Time) []*aggregate {
+	h.start = now
+
+	return h.groups.removeAll()
+}
+
+// evictOverflow removes the oldest groups over the limit
+// note: this function must be called with the hook's mutex locked
+func (h *aggregateHook) evictOverflow() []*aggregate {
+	var closed []*aggregate
+	for h.groups.len() > h.conf.maxGroups {
+		_, agg, _ := h.groups.oldest()
+		h.groups.removeOldest()
+		closed = append(closed, agg)
+	}
+
+	return closed
+}
+
+// report sends the summaries of the groups to the next hook
+func (h *aggregateHook) report(closed []*aggregate, now time.Time) error {
+	var errs []error
+	for _, agg := range closed {
+		summary := syntheticEntry(agg.entry, agg.level, agg.entry.Message, now)
+		for _, name := range h.conf.fields {
+			if value, found := agg.entry.Data[name]; found {
+				summary.Data[name] = value
+			}
+		}
+		summary.Data["count"] = agg.count
+		summary.Data["first"] = agg.first
+		summary.Data["last"] = agg.last
+		for k, num := range agg.numbers {
+			summary.Data[k+"_min"] = num.min
+			summary.Data[k+"_max"] = num.max
+			summary.Data[k+"_sum"] = num.sum
+		}
+
+		errs = append(errs, h.next.Fire(summary))
+	}
+
+	return errors.Join(errs...)
+}
+
+// toFloat converts the value of a numeric field
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case time.Duration:
+		return float64(n), true
+	}
+
+	return 0, false
+}
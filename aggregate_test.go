@@ -0,0 +1,180 @@
+package hooks_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	hooks "logrus-hooks.git"
+	"logrus-hooks.git/hookstest"
+)
+
+func TestAggregate_Window(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	start := clock.Now()
+
+	hook := hooks.AggregateHook(sink,
+		hooks.AggregateWindow(time.Minute),
+		hooks.AggregateFields("route"),
+		hooks.AggregateClock(clock),
+	)
+
+	for i, fields := range []logrus.Fields{
+		{"route": "/a", "latency": 10, "user": "alice"},
+		{"route": "/b", "latency": 5},
+		{"route": "/a", "latency": 30},
+		{"route": "/a", "latency": 20.5},
+	} {
+		level := logrus.InfoLevel
+		if i == 2 {
+			level = logrus.WarnLevel
+		}
+		if err := hook.Fire(newEntry(level, "request served", fields)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+		clock.Advance(10 * time.Second)
+	}
+
+	if sink.Len() != 0 {
+		t.Fatalf("messages were sent before the end of the window: %v", sink.Messages())
+	}
+
+	// the first message after the window ends it
+	clock.Advance(30 * time.Second)
+	if err := hook.Fire(newEntry(logrus.InfoLevel, "next window", nil)); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	entries := sink.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("wrong number of summaries: expected=2, found=%d", len(entries))
+	}
+
+	a, b := entries[0], entries[1]
+	if a.Message != "request served" || a.Level != logrus.WarnLevel {
+		t.Errorf("wrong summary: %s %s", a.Level, a.Message)
+	}
+	expected := logrus.Fields{
+		"route":       "/a",
+		"count":       3,
+		"first":       start,
+		"last":        start.Add(30 * time.Second),
+		"latency_min": 10.0,
+		"latency_max": 30.0,
+		"latency_sum": 60.5,
+	}
+	if len(a.Data) != len(expected) {
+		t.Errorf("wrong fields of the summary: %v", a.Data)
+	}
+	for k, v := range expected {
+		if a.Data[k] != v {
+			t.Errorf("wrong value of field %q: expected=%v, found=%v", k, v, a.Data[k])
+		}
+	}
+	if !a.Time.Equal(start.Add(70 * time.Second)) {
+		t.Errorf("wrong time of the summary: %s", a.Time)
+	}
+
+	if b.Data["route"] != "/b" || b.Data["count"] != 1 || b.Data["latency_sum"] != 5.0 {
+		t.Errorf("wrong summary of the second group: %v", b.Data)
+	}
+
+	// the rest is reported on flush
+	if err := hook.(hooks.Flusher).Flush(); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+	sink.AssertMessages(t, "request served", "request served", "next window")
+}
+
+func TestAggregate_Numeric(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	hook := hooks.AggregateHook(sink, hooks.AggregateNumeric("bytes"), hooks.AggregateClock(newFakeClock()))
+
+	for _, n := range []int{1, 2} {
+		entry := newEntry(logrus.InfoLevel, "sent", logrus.Fields{"bytes": n * 100, "status": 200})
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+	if err := hook.(hooks.Flusher).Flush(); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+
+	sink.AssertField(t, 0, "bytes_sum", 300.0)
+	sink.AssertNoField(t, 0, "status_sum")
+}
+
+func TestAggregate_MaxGroups(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	hook := hooks.AggregateHook(sink, hooks.AggregateMaxGroups(2), hooks.AggregateClock(newFakeClock()))
+
+	for _, msg := range []string{"a", "b", "a", "c"} {
+		if err := hook.Fire(newEntry(logrus.InfoLevel, msg, nil)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	// the oldest group is reported early
+	if sink.AssertMessages(t, "a") {
+		sink.AssertField(t, 0, "count", 2)
+	}
+}
+
+func TestAggregate_Timer(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.AggregateHook(sink, hooks.AggregateWindow(time.Second), hooks.AggregateClock(clock))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the hook: %s", err)
+	}
+
+	if err := hook.Fire(newEntry(logrus.InfoLevel, "tick", nil)); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	// the timer ends the window without more messages, and then waits for
+	// the end of the next window
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+	sink.AssertMessages(t, "tick")
+
+	if err := hook.Fire(newEntry(logrus.InfoLevel, "tock", nil)); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the hook: %s", err)
+	}
+	if hook.IsRunning() {
+		t.Errorf("hook is running after the stop")
+	}
+
+	sink.AssertMessages(t, "tick", "tock")
+}
+
+func TestAggregate_TimerWindow(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.AggregateHook(sink, hooks.AggregateWindow(time.Second), hooks.AggregateClock(clock))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the hook: %s", err)
+	}
+	defer func() { _ = hook.Stop() }()
+
+	// the window starts with the first message, after the timer was started
+	clock.Advance(500 * time.Millisecond)
+	if err := hook.Fire(newEntry(logrus.InfoLevel, "tick", nil)); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	// the timer does not end the window early
+	clock.Advance(500 * time.Millisecond)
+	clock.BlockUntil(1)
+	sink.AssertCount(t, 0)
+
+	clock.Advance(500 * time.Millisecond)
+	clock.BlockUntil(1)
+	sink.AssertMessages(t, "tick")
+}
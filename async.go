@@ -3,6 +3,7 @@ package hooks
 import (
 	"errors"
 	"log"
+	"os"
 	"sync"
 	"sync/atomic"
 
@@ -57,6 +58,10 @@ type asyncHook struct {
 	// nBoostSenders is the number of currently running extra goroutines to
 	// send out the queued messages
 	nBoostSenders uint32
+
+	// metrics receives the events of the hook, it is set when the hook
+	// starts so that the senders do not need the mutex to access it
+	metrics Metrics
 }
 
 // asyncParams defines the performance options of the hook
@@ -64,6 +69,17 @@ type asyncParams struct {
 	numSenders      uint32
 	numBoostSenders uint32
 	bufferLen       uint32
+
+	// metrics receives the events of the hook under the name of the stage
+	metrics Metrics
+	stage   string
+
+	// clock is the source of time of the send latency
+	clock Clock
+
+	// errHandlers receive the errors of the messages sent out by the senders,
+	// the errors are logged to stderr when there are no handlers
+	errHandlers []func(error)
 }
 
 // constructor --------------------------------------------------------
@@ -92,6 +108,31 @@ func BufferLen(n uint32) AsyncOption {
 	}
 }
 
+// AsyncMetrics sets the metrics that receive the events of the hook
+func AsyncMetrics(m Metrics, stage string) AsyncOption {
+	return func(conf *asyncParams) {
+		conf.metrics = m
+		conf.stage = stage
+	}
+}
+
+// AsyncClock sets the clock that measures the send latency
+func AsyncClock(c Clock) AsyncOption {
+	return func(conf *asyncParams) {
+		conf.clock = c
+	}
+}
+
+// AsyncErrorHandler adds a function that receives the errors of the messages
+// sent out by the senders, instead of logging them to stderr
+func AsyncErrorHandler(f func(error)) AsyncOption {
+	return func(conf *asyncParams) {
+		if f != nil {
+			conf.errHandlers = append(conf.errHandlers, f)
+		}
+	}
+}
+
 // AsyncHook creates a Logrus hook that uses goroutines to invoke the next hook
 func AsyncHook(next logrus.Hook, opts ...AsyncOption) RunningHook {
 
@@ -113,6 +154,9 @@ func AsyncHook(next logrus.Hook, opts ...AsyncOption) RunningHook {
 		opt(&hook.conf)
 	}
 
+	hook.errLogger = log.New(os.Stderr, "", log.LstdFlags)
+	hook.metrics = observe(hook.conf.metrics)
+
 	return hook
 }
 
@@ -129,13 +173,23 @@ func (h *asyncHook) Fire(entry *logrus.Entry) error {
 		return ErrNotRunning
 	}
 
+	// the span of the hook, if the message is traced, waits in the queue too
+	entry = startAsyncSpan(entry, h.conf.stage)
+
 	select {
 	case h.messages <- entry:
 		// message was passed to the senders, no error
+		h.metrics.AsyncEnqueue(h.conf.stage, entryLevel(entry))
+		h.metrics.AsyncQueueDepth(h.conf.stage, len(h.messages))
 	default:
 		// buffer is full because senders are too busy or too slow
 		// try to boost the senders if possible
-		return h.boostAndWork(entry)
+		if err := h.boostAndWork(entry); err != nil {
+			h.metrics.AsyncOverflow(h.conf.stage, entryLevel(entry))
+			endAsyncSpan(entry, err)
+			return err
+		}
+		h.metrics.AsyncEnqueue(h.conf.stage, entryLevel(entry))
 	}
 
 	return nil
@@ -154,19 +208,16 @@ func (h *asyncHook) isRunning() bool {
 	return h.running
 }
 
-// Start prepares the hook to send messages via goroutines
+// Start prepares the hook to send messages via goroutines, a running hook
+// is left as it is
 func (h *asyncHook) Start() error {
 	h.Lock()
 	defer h.Unlock()
 
-	h.messages = make(chan *logrus.Entry, h.conf.bufferLen)
-	h.sendersTracker.Add(int(h.conf.numSenders))
-	for i := 0; i < int(h.conf.numSenders); i++ {
-		go h.worker()
+	if !h.isRunning() {
+		h.start()
 	}
 
-	h.running = true
-
 	return nil
 }
 
@@ -175,6 +226,81 @@ func (h *asyncHook) Stop() error {
 	h.Lock()
 	defer h.Unlock()
 
+	if h.isRunning() {
+		h.stop()
+	}
+
+	return nil
+}
+
+// Reconfigure changes the number of senders and the size of the buffer,
+// a running hook sends out the queued messages and starts over
+func (h *asyncHook) Reconfigure(opts ...AsyncOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	// the senders are stopped first because they read the configuration
+	running := h.isRunning()
+	if running {
+		h.stop()
+	}
+
+	for _, opt := range opts {
+		opt(&h.conf)
+	}
+
+	if running {
+		h.start()
+	}
+
+	return nil
+}
+
+// Flush waits for the senders to send out the queued messages, a running
+// hook starts over with new senders
+func (h *asyncHook) Flush() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.isRunning() {
+		h.stop()
+		h.start()
+	}
+
+	return nil
+}
+
+// Inspect describes the configuration of the hook and the state of the senders
+func (h *asyncHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	return map[string]interface{}{
+		"senders":       h.conf.numSenders,
+		"boost_senders": h.conf.numBoostSenders,
+		"buffer_len":    h.conf.bufferLen,
+		"running":       h.isRunning(),
+		"queue_depth":   len(h.messages),
+		"boosters":      atomic.LoadUint32(&h.nBoostSenders),
+	}
+}
+
+// start launches the senders
+// note: this function must be called with the hook's mutex locked
+func (h *asyncHook) start() {
+	h.metrics = observe(h.conf.metrics)
+	h.messages = make(chan *logrus.Entry, h.conf.bufferLen)
+	h.sendersTracker.Add(int(h.conf.numSenders))
+	for i := 0; i < int(h.conf.numSenders); i++ {
+		go h.worker()
+	}
+
+	h.running = true
+}
+
+// stop waits for the senders to send out the queued messages and exit
+// note: this function must be called with the hook's mutex locked
+func (h *asyncHook) stop() {
 	// wait for all booster senders to complete and exit
 	h.boostSendersTracker.Wait()
 
@@ -184,9 +310,10 @@ func (h *asyncHook) Stop() error {
 	// wait for all senders to complete and exit
 	h.sendersTracker.Wait()
 
-	h.running = false
+	// the senders may report the depth out of order, the queue is empty now
+	h.metrics.AsyncQueueDepth(h.conf.stage, 0)
 
-	return nil
+	h.running = false
 }
 
 // worker runs in a loop to send out messages that were queued in the buffer
@@ -194,12 +321,27 @@ func (h *asyncHook) worker() {
 	defer h.sendersTracker.Done()
 
 	for entry := range h.messages {
-		if err := h.next.Fire(entry); err != nil {
-			h.errLogger.Print(err)
+		h.metrics.AsyncQueueDepth(h.conf.stage, len(h.messages))
+		if err := h.send(entry); err != nil {
+			h.reportError(err, "")
 		}
 	}
 }
 
+// send delivers a message to the next hook and reports the time it took
+func (h *asyncHook) send(entry *logrus.Entry) error {
+	traceEvent(entry, "logrus.hook.async.dequeue")
+
+	clock := clockOrSystem(h.conf.clock)
+	start := clock.Now()
+	err := h.next.Fire(entry)
+	h.metrics.AsyncSendLatency(h.conf.stage, entryLevel(entry), clock.Now().Sub(start))
+
+	endAsyncSpan(entry, err)
+
+	return err
+}
+
 // boostAndWork starts an extra goroutine to help empty out the message buffer
 // note: this function must be called with the hook's mutex locked
 func (h *asyncHook) boostAndWork(entry *logrus.Entry) error {
@@ -208,8 +350,9 @@ func (h *asyncHook) boostAndWork(entry *logrus.Entry) error {
 		return ErrBufferFull
 	}
 
-	atomic.AddUint32(&h.nBoostSenders, 1)
+	n := atomic.AddUint32(&h.nBoostSenders, 1)
 	h.boostSendersTracker.Add(1)
+	h.metrics.AsyncBoosters(h.conf.stage, int(n))
 
 	go h.booster(entry)
 
@@ -220,7 +363,8 @@ func (h *asyncHook) boostAndWork(entry *logrus.Entry) error {
 func (h *asyncHook) booster(entry *logrus.Entry) {
 	defer func() {
 		// decrement the number of booster workers by 1
-		atomic.AddUint32(&h.nBoostSenders, ^uint32(0))
+		n := atomic.AddUint32(&h.nBoostSenders, ^uint32(0))
+		h.metrics.AsyncBoosters(h.conf.stage, int(n))
 		h.boostSendersTracker.Done()
 	}()
 
@@ -260,9 +404,23 @@ func (h *asyncHook) booster(entry *logrus.Entry) {
 
 		if haveMessage {
 			// send out the message that was picked up from the buffer
-			if err := h.next.Fire(msg2); err != nil {
-				h.errLogger.Printf("booster worker of async logrus hook: %s", err)
+			h.metrics.AsyncQueueDepth(h.conf.stage, len(h.messages))
+			if err := h.send(msg2); err != nil {
+				h.reportError(err, "booster worker of async logrus hook: ")
 			}
 		}
 	}
 }
+
+// reportError passes the error of a sender to the error handlers, or logs it
+// with the prefix when there are no handlers
+func (h *asyncHook) reportError(err error, prefix string) {
+	if len(h.conf.errHandlers) == 0 {
+		h.errLogger.Print(prefix + err.Error())
+		return
+	}
+
+	for _, f := range h.conf.errHandlers {
+		f(err)
+	}
+}
@@ -205,3 +205,44 @@ func TestAsync_Buffer(t *testing.T) {
 		}
 	}
 }
+
+func TestAsync_Reconfigure(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := AsyncHook(&mockHook, Senders(2), BufferLen(4))
+
+	theHook, ok := hook.(*asyncHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected asyncHook type: %v", hook)
+	}
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	sentMessages := make([]*logrus.Entry, 0)
+	for round, bufferLen := range []uint32{8, 16, 32} {
+		for i := 0; i < 4; i++ {
+			testMessage := logrus.NewEntry(logrus.StandardLogger())
+			testMessage.Message = fmt.Sprintf("test message: %d/%d", round, i)
+
+			if err := hook.Fire(testMessage); err == nil {
+				sentMessages = append(sentMessages, testMessage)
+			}
+		}
+
+		if err := theHook.Reconfigure(BufferLen(bufferLen), Senders(uint32(round+1))); err != nil {
+			t.Fatalf("failed to reconfigure the async hook at round [%d]: %s", round, err)
+		}
+		if !hook.IsRunning() {
+			t.Fatalf("async hook was stopped by the reconfiguration at round [%d]", round)
+		}
+		if n := cap(theHook.messages); n != int(bufferLen) {
+			t.Errorf("wrong buffer length at round [%d]: expected=%d, found=%d", round, bufferLen, n)
+		}
+	}
+
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	mockHook.compare(t, sentMessages)
+}
@@ -0,0 +1,224 @@
+package hooks
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// default number of messages per key and the time to live of the keys
+	defaultBufferSize = 100
+	defaultBufferTTL  = time.Minute
+)
+
+// bufferOnErrorHook is a Logrus hook that holds back low level messages
+// until a message of a higher level arrives
+type bufferOnErrorHook struct {
+	sync.Mutex
+
+	ChainElement
+	conf bufferParams
+	key  KeyFunc
+
+	// buffers holds the messages of the recently seen keys
+	buffers *keyCache[*entryRing]
+
+	// droppedKeys counts the buffers that were dropped without a trigger
+	droppedKeys uint64
+}
+
+// bufferParams defines the buffers of the hook
+type bufferParams struct {
+	trigger logrus.Level
+	size    int
+	maxKeys int
+	ttl     time.Duration
+	clock   Clock
+}
+
+// entryRing keeps the last messages of a key, the oldest ones are dropped
+type entryRing struct {
+	entries []*logrus.Entry
+	next    int
+	full    bool
+}
+
+// constructor --------------------------------------------------------
+
+// BufferOption is a functional option to update the buffer hook configuration
+type BufferOption func(conf *bufferParams)
+
+// TriggerLevel sets the log level that sends out the buffered messages, the
+// messages of this level and the levels that are more severe trigger it
+func TriggerLevel(level logrus.Level) BufferOption {
+	return func(conf *bufferParams) {
+		conf.trigger = level
+	}
+}
+
+// BufferSize sets the number of messages kept for each key
+func BufferSize(n int) BufferOption {
+	return func(conf *bufferParams) {
+		if n > 0 {
+			conf.size = n
+		}
+	}
+}
+
+// BufferMaxKeys sets the maximum number of keys that have buffers, the least
+// recently used keys are dropped first
+func BufferMaxKeys(n int) BufferOption {
+	return func(conf *bufferParams) {
+		if n >= 0 {
+			conf.maxKeys = n
+		}
+	}
+}
+
+// BufferTTL sets the time after which the buffer of a key that had no
+// messages is dropped
+func BufferTTL(d time.Duration) BufferOption {
+	return func(conf *bufferParams) {
+		if d >= 0 {
+			conf.ttl = d
+		}
+	}
+}
+
+// BufferClock sets the clock that expires the buffers of the hook
+func BufferClock(c Clock) BufferOption {
+	return func(conf *bufferParams) {
+		conf.clock = c
+	}
+}
+
+// BufferOnErrorHook creates a Logrus hook that holds back the messages of
+// each key, like a request id, until a message at the trigger level arrives
+//
+// The trigger message is sent to the next hook right after the messages of
+// its key that were held back, so the context of an error is not lost. The
+// buffers of the keys that never see a trigger are dropped. The hook fires
+// for all log levels, whatever the levels of the next hook are.
+func BufferOnErrorHook(next logrus.Hook, key KeyFunc, opts ...BufferOption) logrus.Hook {
+
+	hook := &bufferOnErrorHook{
+		ChainElement: ChainElement{
+			next: next,
+		},
+		// default configuration
+		conf: bufferParams{
+			trigger: logrus.ErrorLevel,
+			size:    defaultBufferSize,
+			maxKeys: defaultMaxKeys,
+			ttl:     defaultBufferTTL,
+		},
+		key: key,
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	hook.conf.clock = clockOrSystem(hook.conf.clock)
+	hook.buffers = newKeyCache[*entryRing](hook.conf.maxKeys, hook.conf.ttl)
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire holds back the message, or sends out the buffer of its key when the
+// message is at the trigger level
+func (h *bufferOnErrorHook) Fire(entry *logrus.Entry) error {
+	now := h.conf.clock.Now()
+
+	key := ""
+	if entry != nil {
+		key = h.key(entry)
+	}
+
+	h.Lock()
+	n := h.buffers.len()
+	h.buffers.expire(now)
+	h.droppedKeys += uint64(n - h.buffers.len())
+
+	ring, found := h.buffers.get(key, now)
+	if entry != nil && entry.Level > h.conf.trigger {
+		if !found {
+			ring = newEntryRing(h.conf.size)
+			n = h.buffers.len()
+			h.buffers.add(key, ring, now)
+			h.droppedKeys += uint64(n + 1 - h.buffers.len())
+		}
+		ring.push(copyEntry(entry))
+		h.Unlock()
+
+		return nil
+	}
+
+	var held []*logrus.Entry
+	if found {
+		held = ring.list()
+		h.buffers.remove(key)
+	}
+	h.Unlock()
+
+	var errs []error
+	for _, e := range held {
+		errs = append(errs, h.next.Fire(e))
+	}
+	errs = append(errs, h.next.Fire(entry))
+
+	return errors.Join(errs...)
+}
+
+// Levels are all log levels, the low levels are needed to fill the buffers
+func (h *bufferOnErrorHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Inspect describes the configuration of the hook and the number of buffers
+func (h *bufferOnErrorHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	return map[string]interface{}{
+		"trigger":      h.conf.trigger.String(),
+		"size":         h.conf.size,
+		"max_keys":     h.conf.maxKeys,
+		"ttl":          h.conf.ttl.String(),
+		"keys":         h.buffers.len(),
+		"dropped_keys": h.droppedKeys,
+	}
+}
+
+// newEntryRing creates an empty buffer for n messages
+func newEntryRing(n int) *entryRing {
+	return &entryRing{
+		entries: make([]*logrus.Entry, n),
+	}
+}
+
+// push adds a message to the buffer, the oldest message is dropped to make
+// room for it when the buffer is full
+func (r *entryRing) push(entry *logrus.Entry) {
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the messages from the oldest to the newest
+func (r *entryRing) list() []*logrus.Entry {
+	var entries []*logrus.Entry
+	if r.full {
+		entries = append(entries, r.entries[r.next:]...)
+	}
+	entries = append(entries, r.entries[:r.next]...)
+
+	return entries
+}
@@ -0,0 +1,99 @@
+package hooks
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newBufferEntry(level logrus.Level, msg, requestID string) *logrus.Entry {
+	return newTestEntry(level, msg, logrus.Fields{"request_id": requestID})
+}
+
+func TestBufferOnError_Trigger(t *testing.T) {
+	var mockHook mockOrderedHook
+	hook := BufferOnErrorHook(&mockHook, KeyFields("request_id"), BufferSize(3))
+
+	entries := []*logrus.Entry{
+		newBufferEntry(logrus.DebugLevel, "a1", "a"),
+		newBufferEntry(logrus.DebugLevel, "b1", "b"),
+		newBufferEntry(logrus.InfoLevel, "a2", "a"),
+		newBufferEntry(logrus.DebugLevel, "a3", "a"),
+		newBufferEntry(logrus.WarnLevel, "a4", "a"),
+		newBufferEntry(logrus.ErrorLevel, "a5", "a"),
+		newBufferEntry(logrus.DebugLevel, "a6", "a"),
+		newBufferEntry(logrus.FatalLevel, "a7", "a"),
+	}
+	for _, entry := range entries {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	// the oldest message of a full buffer is dropped, the buffer starts over
+	// after the trigger and the messages of the other keys are held back
+	expected := "[a2 a3 a4 a5 a6 a7]"
+	if messages := fmt.Sprint(mockHook.messages); messages != expected {
+		t.Errorf("wrong messages: expected=%s, found=%s", expected, messages)
+	}
+
+	state := hook.(Inspector).Inspect()
+	if state["keys"] != 1 {
+		t.Errorf("wrong number of buffers: %v", state["keys"])
+	}
+}
+
+func TestBufferOnError_TriggerLevel(t *testing.T) {
+	var mockHook mockOrderedHook
+	hook := BufferOnErrorHook(&mockHook, KeyFields("request_id"), TriggerLevel(logrus.WarnLevel))
+
+	for _, entry := range []*logrus.Entry{
+		newBufferEntry(logrus.DebugLevel, "a1", "a"),
+		newBufferEntry(logrus.WarnLevel, "a2", "a"),
+	} {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	if messages := fmt.Sprint(mockHook.messages); messages != "[a1 a2]" {
+		t.Errorf("buffer was not sent out by a warning: %s", messages)
+	}
+	if levels := hook.Levels(); len(levels) != len(logrus.AllLevels) {
+		t.Errorf("hook does not fire for all levels: %v", levels)
+	}
+}
+
+func TestBufferOnError_Goroutine(t *testing.T) {
+	var mockHook mockOrderedHook
+	hook := BufferOnErrorHook(&mockHook, KeyGoroutine())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_ = hook.Fire(newBufferEntry(logrus.DebugLevel, fmt.Sprintf("debug-%d", i), ""))
+			if i == 0 {
+				_ = hook.Fire(newBufferEntry(logrus.ErrorLevel, "error-0", ""))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if messages := fmt.Sprint(mockHook.messages); messages != "[debug-0 error-0]" {
+		t.Errorf("wrong messages of the goroutine: %s", messages)
+	}
+}
+
+func TestBufferOnError_Nil(t *testing.T) {
+	var mockHook mockCannedHook
+	hook := BufferOnErrorHook(&mockHook, KeyMessage())
+
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("fire failed: %s", err)
+	}
+}
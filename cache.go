@@ -0,0 +1,192 @@
+package hooks
+
+import (
+	"container/list"
+	"time"
+)
+
+// keyCache is a map of limited size that evicts the least recently used keys
+//
+// The keys that were not used for longer than the time-to-live are evicted
+// as well. The cache is NOT safe for concurrent access.
+type keyCache[V any] struct {
+	maxKeys int
+	ttl     time.Duration
+
+	// items maps the keys to the elements of the usage list
+	items map[string]*list.Element
+
+	// order is the usage list, the least recently used key is at the front
+	order *list.List
+
+	// onEvict is called with the keys that the cache evicts on its own, the
+	// keys that are removed explicitly are not reported
+	onEvict func(key string, value V)
+}
+
+// cacheItem is an element of the usage list
+type cacheItem[V any] struct {
+	key   string
+	value V
+	used  time.Time
+}
+
+// newKeyCache creates a cache, zero maxKeys or ttl means no limit
+func newKeyCache[V any](maxKeys int, ttl time.Duration) *keyCache[V] {
+	return &keyCache[V]{
+		maxKeys: maxKeys,
+		ttl:     ttl,
+		items:   make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get looks up a key and marks it as recently used
+func (c *keyCache[V]) get(key string, now time.Time) (V, bool) {
+	el, found := c.items[key]
+	if !found {
+		var zero V
+		return zero, false
+	}
+
+	item := el.Value.(*cacheItem[V])
+	item.used = now
+	c.order.MoveToBack(el)
+
+	return item.value, true
+}
+
+// peek looks up a key without marking it as recently used
+func (c *keyCache[V]) peek(key string) (V, bool) {
+	el, found := c.items[key]
+	if !found {
+		var zero V
+		return zero, false
+	}
+
+	return el.Value.(*cacheItem[V]).value, true
+}
+
+// oldest looks up the least recently used key
+func (c *keyCache[V]) oldest() (string, V, bool) {
+	el := c.order.Front()
+	if el == nil {
+		var zero V
+		return "", zero, false
+	}
+
+	item := el.Value.(*cacheItem[V])
+	return item.key, item.value, true
+}
+
+// removeOldest deletes the least recently used key
+func (c *keyCache[V]) removeOldest() {
+	if el := c.order.Front(); el != nil {
+		c.removeElement(el)
+	}
+}
+
+// remove deletes a key
+func (c *keyCache[V]) remove(key string) {
+	if el, found := c.items[key]; found {
+		c.removeElement(el)
+	}
+}
+
+// removeAll empties the cache and returns the values from the least to the
+// most recently used
+func (c *keyCache[V]) removeAll() []V {
+	values := make([]V, 0, c.order.Len())
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		values = append(values, el.Value.(*cacheItem[V]).value)
+	}
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+
+	return values
+}
+
+// add stores the value of a key and evicts the least recently used keys
+// if the cache grows over its limit
+func (c *keyCache[V]) add(key string, value V, now time.Time) {
+	if el, found := c.items[key]; found {
+		item := el.Value.(*cacheItem[V])
+		item.value = value
+		item.used = now
+		c.order.MoveToBack(el)
+		return
+	}
+
+	c.items[key] = c.order.PushBack(&cacheItem[V]{
+		key:   key,
+		value: value,
+		used:  now,
+	})
+
+	for c.maxKeys > 0 && c.order.Len() > c.maxKeys {
+		c.evict(c.order.Front())
+	}
+}
+
+// expire evicts the keys that were not used for longer than the time-to-live
+func (c *keyCache[V]) expire(now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	for el := c.order.Front(); el != nil; el = c.order.Front() {
+		if now.Sub(el.Value.(*cacheItem[V]).used) < c.ttl {
+			// the rest of the keys were used more recently
+			return
+		}
+		c.evict(el)
+	}
+}
+
+// resize changes the limits of the cache and evicts the keys over the limit
+func (c *keyCache[V]) resize(maxKeys int, ttl time.Duration) {
+	c.maxKeys = maxKeys
+	c.ttl = ttl
+
+	for c.maxKeys > 0 && c.order.Len() > c.maxKeys {
+		c.evict(c.order.Front())
+	}
+}
+
+// replace updates the values of all keys, without changing their order
+func (c *keyCache[V]) replace(fn func(value V) V) {
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		item := el.Value.(*cacheItem[V])
+		item.value = fn(item.value)
+	}
+}
+
+// each calls the function with all keys, from the least to the most
+// recently used
+func (c *keyCache[V]) each(fn func(key string, value V)) {
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		item := el.Value.(*cacheItem[V])
+		fn(item.key, item.value)
+	}
+}
+
+// len is the number of keys in the cache
+func (c *keyCache[V]) len() int {
+	return c.order.Len()
+}
+
+// removeElement deletes an element from the cache
+func (c *keyCache[V]) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*cacheItem[V]).key)
+}
+
+// evict deletes an element that is over the limits of the cache
+func (c *keyCache[V]) evict(el *list.Element) {
+	c.removeElement(el)
+	if c.onEvict != nil {
+		item := el.Value.(*cacheItem[V])
+		c.onEvict(item.key, item.value)
+	}
+}
@@ -0,0 +1,154 @@
+package hooks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKeyCache_MaxKeys(t *testing.T) {
+	testData := []int{
+		1, 10, 25, 50, 100,
+	}
+
+	now := time.Now()
+	for _, td := range testData {
+		cache := newKeyCache[int](td, 0)
+
+		for i := 0; i < 2*td; i++ {
+			cache.add(fmt.Sprintf("key-%d", i), i, now)
+			if cache.len() > td {
+				t.Fatalf("cache grew over its limit [%d] at round [%d]: %d keys", td, i, cache.len())
+			}
+		}
+
+		// only the most recently added keys are left
+		for i := 0; i < 2*td; i++ {
+			_, found := cache.get(fmt.Sprintf("key-%d", i), now)
+			if expected := i >= td; found != expected {
+				t.Errorf("limit=%d, key [%d] found=%t, expected=%t", td, i, found, expected)
+			}
+		}
+	}
+}
+
+func TestKeyCache_LRU(t *testing.T) {
+	now := time.Now()
+	cache := newKeyCache[int](2, 0)
+
+	cache.add("a", 1, now)
+	cache.add("b", 2, now)
+
+	// using "a" makes "b" the least recently used key
+	if value, found := cache.get("a", now); !found || value != 1 {
+		t.Fatalf("unexpected value of key a: found=%t, value=%d", found, value)
+	}
+	cache.add("c", 3, now)
+
+	if _, found := cache.get("b", now); found {
+		t.Errorf("least recently used key was not evicted")
+	}
+	if _, found := cache.get("a", now); !found {
+		t.Errorf("recently used key was evicted")
+	}
+}
+
+func TestKeyCache_TTL(t *testing.T) {
+	now := time.Now()
+	cache := newKeyCache[int](0, time.Minute)
+
+	cache.add("a", 1, now)
+	cache.add("b", 2, now.Add(30*time.Second))
+
+	cache.expire(now.Add(59 * time.Second))
+	if cache.len() != 2 {
+		t.Fatalf("keys expired too early: %d keys left", cache.len())
+	}
+
+	cache.expire(now.Add(time.Minute))
+	if _, found := cache.get("a", now); found {
+		t.Errorf("key a did not expire")
+	}
+	if _, found := cache.get("b", now); !found {
+		t.Errorf("key b expired too early")
+	}
+}
+
+func TestKeyCache_Oldest(t *testing.T) {
+	now := time.Now()
+	cache := newKeyCache[int](0, 0)
+
+	if _, _, found := cache.oldest(); found {
+		t.Fatalf("empty cache has an oldest key")
+	}
+
+	cache.add("a", 1, now)
+	cache.add("b", 2, now)
+
+	// peek does not change the order of the keys
+	if value, found := cache.peek("a"); !found || value != 1 {
+		t.Fatalf("unexpected value of key a: found=%t, value=%d", found, value)
+	}
+	if key, _, _ := cache.oldest(); key != "a" {
+		t.Errorf("wrong oldest key: expected=a, found=%s", key)
+	}
+
+	cache.removeOldest()
+	if key, _, _ := cache.oldest(); key != "b" {
+		t.Errorf("wrong oldest key: expected=b, found=%s", key)
+	}
+
+	cache.add("c", 3, now)
+	if values := cache.removeAll(); len(values) != 2 || values[0] != 2 || values[1] != 3 {
+		t.Errorf("unexpected values of the removed keys: %v", values)
+	}
+	if cache.len() != 0 {
+		t.Errorf("cache is not empty: %d keys", cache.len())
+	}
+}
+
+func TestKeyCache_Remove(t *testing.T) {
+	now := time.Now()
+	cache := newKeyCache[int](0, 0)
+
+	cache.add("a", 1, now)
+	cache.add("b", 2, now)
+	cache.remove("a")
+	cache.remove("missing")
+
+	if _, found := cache.get("a", now); found || cache.len() != 1 {
+		t.Errorf("key was not removed: %d keys", cache.len())
+	}
+	if key, _, _ := cache.oldest(); key != "b" {
+		t.Errorf("wrong oldest key after the removal: %s", key)
+	}
+}
+
+func TestKeyCache_OnEvict(t *testing.T) {
+	now := time.Now()
+	cache := newKeyCache[int](2, time.Minute)
+
+	evicted := map[string]int{}
+	cache.onEvict = func(key string, value int) {
+		evicted[key] = value
+	}
+
+	cache.add("a", 1, now)
+	cache.add("b", 2, now.Add(time.Second))
+	cache.add("c", 3, now.Add(time.Second))
+	if len(evicted) != 1 || evicted["a"] != 1 {
+		t.Fatalf("least recently used key was not reported: %v", evicted)
+	}
+
+	cache.expire(now.Add(time.Minute + time.Second))
+	if len(evicted) != 3 || evicted["b"] != 2 || evicted["c"] != 3 {
+		t.Fatalf("expired keys were not reported: %v", evicted)
+	}
+
+	// the keys removed explicitly are not evicted
+	cache.add("d", 4, now)
+	cache.remove("d")
+	if _, found := evicted["d"]; found {
+		t.Errorf("removed key was reported as evicted")
+	}
+}
@@ -0,0 +1,330 @@
+package hooks
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrChaos is the error of the failures injected by the chaos hook,
+	// unless the hook is configured with other errors
+	ErrChaos = errors.New("logrus hook failure injected by chaos hook")
+
+	// ErrChaosPanic is the value of the panics injected by the chaos hook
+	ErrChaosPanic = errors.New("logrus hook panic injected by chaos hook")
+)
+
+// LatencyDistribution draws the latency of a message from a random source
+type LatencyDistribution func(r *rand.Rand) time.Duration
+
+// chaosHook is a Logrus hook that injects failures into the chain it wraps
+type chaosHook struct {
+	sync.Mutex
+
+	ChainImpl
+	conf chaosParams
+
+	// rng decides which faults are injected, it is not safe for concurrent
+	// use and is guarded by the mutex of the hook
+	rng *rand.Rand
+}
+
+// chaosParams defines the faults injected by the hook
+type chaosParams struct {
+	enabled bool
+
+	// errorRate is the fraction of messages that fail with one of the errors
+	errorRate float64
+	errs      []error
+
+	// latencyRate is the fraction of messages that are delayed by a latency
+	// drawn from the distribution
+	latencyRate float64
+	latency     LatencyDistribution
+
+	// panicRate is the fraction of messages that cause a panic
+	panicRate float64
+
+	// stallRate is the fraction of messages that stall for the duration
+	stallRate float64
+	stall     time.Duration
+
+	// seed is set when the random source must be reproducible
+	seed   uint64
+	seeded bool
+	reseed bool
+
+	// clock is the source of the delays
+	clock Clock
+}
+
+// chaosFaults are the faults drawn for one message
+type chaosFaults struct {
+	delay time.Duration
+	panic bool
+	err   error
+}
+
+// constructor --------------------------------------------------------
+
+// ChaosOption is a functional option to update the chaos hook configuration
+type ChaosOption func(conf *chaosParams)
+
+// ChaosEnabled switches the injection of faults on or off, the messages pass
+// through unchanged while it is off
+func ChaosEnabled(enabled bool) ChaosOption {
+	return func(conf *chaosParams) {
+		conf.enabled = enabled
+	}
+}
+
+// ErrorRate sets the fraction, between 0 and 1, of the messages that fail
+//
+// The failures return one of the errors, picked at random, or ErrChaos when
+// no errors are given.
+func ErrorRate(rate float64, errs ...error) ChaosOption {
+	return func(conf *chaosParams) {
+		conf.errorRate = clampRate(rate)
+		conf.errs = append([]error(nil), errs...)
+	}
+}
+
+// LatencyRate sets the fraction, between 0 and 1, of the messages that are
+// delayed by a latency drawn from the distribution
+func LatencyRate(rate float64, dist LatencyDistribution) ChaosOption {
+	return func(conf *chaosParams) {
+		if dist != nil {
+			conf.latencyRate = clampRate(rate)
+			conf.latency = dist
+		}
+	}
+}
+
+// PanicRate sets the fraction, between 0 and 1, of the messages that cause a
+// panic with ErrChaosPanic
+func PanicRate(rate float64) ChaosOption {
+	return func(conf *chaosParams) {
+		conf.panicRate = clampRate(rate)
+	}
+}
+
+// StallRate sets the fraction, between 0 and 1, of the messages that stall
+// for the duration before they are passed on
+func StallRate(rate float64, d time.Duration) ChaosOption {
+	return func(conf *chaosParams) {
+		if d >= 0 {
+			conf.stallRate = clampRate(rate)
+			conf.stall = d
+		}
+	}
+}
+
+// ChaosSeed seeds the random source of the hook, so that the same messages
+// get the same faults in every run
+func ChaosSeed(seed uint64) ChaosOption {
+	return func(conf *chaosParams) {
+		conf.seed = seed
+		conf.seeded = true
+		conf.reseed = true
+	}
+}
+
+// ChaosClock sets the clock of the latency and the stalls
+func ChaosClock(c Clock) ChaosOption {
+	return func(conf *chaosParams) {
+		conf.clock = c
+	}
+}
+
+// FixedLatency is a latency distribution that always returns d
+func FixedLatency(d time.Duration) LatencyDistribution {
+	return func(*rand.Rand) time.Duration {
+		return d
+	}
+}
+
+// UniformLatency is a latency distribution that is uniform between min and max
+func UniformLatency(min, max time.Duration) LatencyDistribution {
+	if max < min {
+		min, max = max, min
+	}
+
+	return func(r *rand.Rand) time.Duration {
+		if max == min {
+			return min
+		}
+		return min + time.Duration(r.Int64N(int64(max-min)))
+	}
+}
+
+// NormalLatency is a latency distribution that is normal with the mean and
+// the standard deviation, negative latencies are cut off at zero
+func NormalLatency(mean, stddev time.Duration) LatencyDistribution {
+	return func(r *rand.Rand) time.Duration {
+		d := float64(mean) + r.NormFloat64()*float64(stddev)
+		return time.Duration(math.Max(0, d))
+	}
+}
+
+// ExponentialLatency is a latency distribution that is exponential with the
+// mean, most messages are fast and a few of them are very slow
+func ExponentialLatency(mean time.Duration) LatencyDistribution {
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(r.ExpFloat64() * float64(mean))
+	}
+}
+
+// ChaosHook creates a Logrus hook that injects faults into the chain it wraps
+//
+// Every message can stall, be delayed by a random latency, cause a panic or
+// fail, each with its own rate, in that order. The messages that do not fail
+// are passed on to the next hook. The faults can be switched off and on at
+// runtime with Reconfigure and ChaosEnabled.
+//
+// The panics are not recovered by the other hooks, they reach the code that
+// logged the message.
+func ChaosHook(next logrus.Hook, opts ...ChaosOption) logrus.Hook {
+
+	hook := &chaosHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: chaosParams{
+			enabled: true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	hook.rng = hook.conf.newRand()
+	hook.conf.reseed = false
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire injects the faults drawn for the message and passes it on to the
+// next hook unless it fails
+func (h *chaosHook) Fire(entry *logrus.Entry) error {
+
+	h.Lock()
+	conf := h.conf
+	faults := h.draw()
+	h.Unlock()
+
+	if faults.delay > 0 {
+		clockOrSystem(conf.clock).Sleep(faults.delay)
+	}
+	if faults.panic {
+		panic(ErrChaosPanic)
+	}
+	if faults.err != nil {
+		return faults.err
+	}
+
+	return h.next.Fire(entry)
+}
+
+// Reconfigure changes the faults injected by the hook, a new seed restarts
+// the random source
+func (h *chaosHook) Reconfigure(opts ...ChaosOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	conf := h.conf
+	conf.errs = append([]error(nil), h.conf.errs...)
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	if conf.reseed {
+		h.rng = conf.newRand()
+		conf.reseed = false
+	}
+	h.conf = conf
+
+	return nil
+}
+
+// Inspect describes the faults injected by the hook
+func (h *chaosHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	errs := make([]string, 0, len(h.conf.errs))
+	for _, err := range h.conf.errs {
+		errs = append(errs, err.Error())
+	}
+
+	return map[string]interface{}{
+		"enabled":      h.conf.enabled,
+		"error_rate":   h.conf.errorRate,
+		"errors":       errs,
+		"latency_rate": h.conf.latencyRate,
+		"panic_rate":   h.conf.panicRate,
+		"stall_rate":   h.conf.stallRate,
+		"stall":        h.conf.stall.String(),
+	}
+}
+
+// draw decides the faults of a message, it is called with the lock held
+func (h *chaosHook) draw() chaosFaults {
+	var faults chaosFaults
+	if !h.conf.enabled {
+		return faults
+	}
+
+	if h.hit(h.conf.stallRate) {
+		faults.delay += h.conf.stall
+	}
+	if h.hit(h.conf.latencyRate) {
+		faults.delay += h.conf.latency(h.rng)
+	}
+	faults.panic = h.hit(h.conf.panicRate)
+	if h.hit(h.conf.errorRate) {
+		faults.err = ErrChaos
+		if n := len(h.conf.errs); n > 0 {
+			faults.err = h.conf.errs[h.rng.IntN(n)]
+		}
+	}
+
+	return faults
+}
+
+// hit decides whether a fault with the rate is injected, the random source
+// is used only for rates between 0 and 1
+func (h *chaosHook) hit(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+
+	return h.rng.Float64() < rate
+}
+
+// newRand creates the random source of the hook, seeded if requested
+func (conf *chaosParams) newRand() *rand.Rand {
+	if conf.seeded {
+		return rand.New(rand.NewPCG(conf.seed, conf.seed))
+	}
+
+	return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+}
+
+// clampRate limits a rate to the range between 0 and 1
+func clampRate(rate float64) float64 {
+	return math.Max(0, math.Min(1, rate))
+}
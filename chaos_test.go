@@ -0,0 +1,253 @@
+package hooks_test
+
+import (
+	"errors"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	hooks "logrus-hooks.git"
+	"logrus-hooks.git/hookstest"
+)
+
+// errSinkDown is a custom error injected by the chaos hook
+var errSinkDown = errors.New("sink is down")
+
+func newChaosClock() *hookstest.FakeClock {
+	return hookstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestChaos_ErrorRate(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	hook := hooks.ChaosHook(sink, hooks.ErrorRate(0.3), hooks.ChaosSeed(42))
+
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if err := hook.Fire(nil); err != nil {
+			if !errors.Is(err, hooks.ErrChaos) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			failed++
+		}
+	}
+
+	if failed < 250 || failed > 350 {
+		t.Errorf("wrong number of failures: expected=~300, found=%d", failed)
+	}
+	sink.AssertCount(t, 1000-failed)
+}
+
+func TestChaos_Seed(t *testing.T) {
+	results := func() []bool {
+		hook := hooks.ChaosHook(hookstest.NewRecorder(), hooks.ErrorRate(0.5), hooks.ChaosSeed(7))
+
+		var failures []bool
+		for i := 0; i < 100; i++ {
+			failures = append(failures, hook.Fire(nil) != nil)
+		}
+		return failures
+	}
+
+	first, second := results(), results()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("the same seed injected different faults at message [%d]", i)
+		}
+	}
+}
+
+func TestChaos_CustomErrors(t *testing.T) {
+	errTimeout := &hooks.RateLimitError{Limit: 1, Burst: 1}
+	hook := hooks.ChaosHook(nil, hooks.ErrorRate(1, errSinkDown, errTimeout), hooks.ChaosSeed(1))
+
+	var down, limited int
+	for i := 0; i < 100; i++ {
+		err := hook.Fire(nil)
+
+		var limitErr *hooks.RateLimitError
+		switch {
+		case errors.Is(err, errSinkDown):
+			down++
+		case errors.As(err, &limitErr):
+			limited++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if down == 0 || limited == 0 {
+		t.Errorf("not all errors were injected: down=%d, rate limited=%d", down, limited)
+	}
+}
+
+func TestChaos_Latency(t *testing.T) {
+	clock := newChaosClock()
+	clock.SetAutoAdvance(true)
+
+	start := clock.Now()
+	hook := hooks.ChaosHook(hookstest.NewRecorder(),
+		hooks.LatencyRate(1, hooks.FixedLatency(time.Second)),
+		hooks.StallRate(0.5, time.Minute),
+		hooks.ChaosSeed(3),
+		hooks.ChaosClock(clock),
+	)
+
+	for i := 0; i < 10; i++ {
+		if err := hook.Fire(nil); err != nil {
+			t.Fatalf("latency caused an error: %s", err)
+		}
+	}
+
+	elapsed := clock.Now().Sub(start)
+	stalls := (elapsed - 10*time.Second) / time.Minute
+	if elapsed%time.Minute != 10*time.Second || stalls == 0 || stalls == 10 {
+		t.Errorf("wrong total delay of 10 messages with 1s latency and 50%% stalls: %s", elapsed)
+	}
+}
+
+func TestChaos_Stall(t *testing.T) {
+	clock := newChaosClock()
+	sink := hookstest.NewRecorder()
+	hook := hooks.ChaosHook(sink, hooks.StallRate(1, time.Hour), hooks.ChaosClock(clock))
+
+	done := make(chan error)
+	go func() {
+		done <- hook.Fire(nil)
+	}()
+
+	clock.BlockUntil(1)
+	sink.AssertCount(t, 0)
+
+	clock.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Errorf("stalled message failed: %s", err)
+	}
+	sink.AssertCount(t, 1)
+}
+
+func TestChaos_LatencyDistributions(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 1))
+
+	uniform := hooks.UniformLatency(time.Second, 2*time.Second)
+	normal := hooks.NormalLatency(time.Second, 2*time.Second)
+	exponential := hooks.ExponentialLatency(time.Second)
+
+	var sumExp time.Duration
+	for i := 0; i < 1000; i++ {
+		if d := uniform(r); d < time.Second || d >= 2*time.Second {
+			t.Fatalf("uniform latency out of range: %s", d)
+		}
+		if d := normal(r); d < 0 {
+			t.Fatalf("negative normal latency: %s", d)
+		}
+		sumExp += exponential(r)
+	}
+
+	if mean := sumExp / 1000; mean < 800*time.Millisecond || mean > 1200*time.Millisecond {
+		t.Errorf("wrong mean of exponential latency: expected=~1s, found=%s", mean)
+	}
+	if d := hooks.UniformLatency(time.Second, time.Second)(r); d != time.Second {
+		t.Errorf("wrong latency of an empty range: %s", d)
+	}
+}
+
+func TestChaos_Panic(t *testing.T) {
+	hook := hooks.ChaosHook(hookstest.NewRecorder(), hooks.PanicRate(1))
+
+	defer func() {
+		if r, ok := recover().(error); !ok || !errors.Is(r, hooks.ErrChaosPanic) {
+			t.Errorf("unexpected panic: %v", r)
+		}
+	}()
+
+	_ = hook.Fire(nil)
+	t.Errorf("chaos hook did not panic")
+}
+
+func TestChaos_Reconfigure(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	hook := hooks.ChaosHook(sink, hooks.ErrorRate(1), hooks.PanicRate(1), hooks.ChaosEnabled(false))
+	chaos := hook.(hooks.Reconfigurable[hooks.ChaosOption])
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("disabled chaos hook failed: %s", err)
+	}
+
+	_ = chaos.Reconfigure(hooks.ChaosEnabled(true), hooks.PanicRate(0))
+	if err := hook.Fire(nil); !errors.Is(err, hooks.ErrChaos) {
+		t.Errorf("enabled chaos hook did not fail: %v", err)
+	}
+
+	_ = chaos.Reconfigure(hooks.ErrorRate(0))
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("chaos hook without faults failed: %s", err)
+	}
+	sink.AssertCount(t, 2)
+
+	state := hook.(hooks.Inspector).Inspect()
+	if state["enabled"] != true || state["error_rate"] != 0.0 || state["panic_rate"] != 0.0 {
+		t.Errorf("wrong state of the chaos hook: %v", state)
+	}
+}
+
+func TestChaos_Retry(t *testing.T) {
+	clock := newChaosClock()
+	clock.SetAutoAdvance(true)
+
+	sink := hookstest.NewRecorder()
+	chaos := hooks.ChaosHook(sink, hooks.ErrorRate(0.5), hooks.ChaosSeed(11))
+	hook := hooks.RetryHook(chaos, time.Second, hooks.Retries(10), hooks.RetryClock(clock))
+
+	// half of the attempts fail, the retries deliver all messages
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	for i := 0; i < 100; i++ {
+		entry.Message = "message"
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("message [%d] was not delivered: %s", i, err)
+		}
+	}
+	sink.AssertCount(t, 100)
+
+	// the retries give up when the sink is down
+	_ = chaos.(hooks.Reconfigurable[hooks.ChaosOption]).Reconfigure(hooks.ErrorRate(1, errSinkDown))
+
+	var retryErr *hooks.RetryExhaustedError
+	err := hook.Fire(entry)
+	if !errors.As(err, &retryErr) || !errors.Is(err, errSinkDown) {
+		t.Fatalf("unexpected error of a sink that is down: %v", err)
+	}
+	if retryErr.Attempts != 11 {
+		t.Errorf("wrong number of attempts: expected=11, found=%d", retryErr.Attempts)
+	}
+}
+
+func TestChaos_Async(t *testing.T) {
+	clock := newChaosClock()
+	sink := hookstest.NewRecorder()
+	chaos := hooks.ChaosHook(sink, hooks.StallRate(1, time.Minute), hooks.ChaosClock(clock))
+	hook := hooks.AsyncHook(chaos, hooks.Senders(1), hooks.BoostSenders(0), hooks.BufferLen(2))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+
+	// the stalled sender leaves room for two messages in the buffer
+	_ = hook.Fire(nil)
+	clock.BlockUntil(1)
+	for i := 0; i < 2; i++ {
+		if err := hook.Fire(nil); err != nil {
+			t.Fatalf("message [%d] was not queued: %s", i, err)
+		}
+	}
+	if err := hook.Fire(nil); !errors.Is(err, hooks.ErrBufferFull) {
+		t.Errorf("full buffer did not drop the message: %v", err)
+	}
+
+	_ = chaos.(hooks.Reconfigurable[hooks.ChaosOption]).Reconfigure(hooks.ChaosEnabled(false))
+	clock.Advance(time.Minute)
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop: %s", err)
+	}
+	sink.AssertCount(t, 3)
+}
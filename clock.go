@@ -0,0 +1,72 @@
+package hooks
+
+import "time"
+
+// Clock is the source of time of the hooks, tests can replace it with a
+// clock that is moved forward by hand
+type Clock interface {
+	// Now is the current time
+	Now() time.Time
+
+	// Sleep pauses the current goroutine for the duration
+	Sleep(d time.Duration)
+
+	// NewTimer creates a timer that fires once after the duration
+	NewTimer(d time.Duration) Timer
+
+	// After waits for the duration and then sends the current time on the channel
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a single event of a Clock, like time.Timer
+type Timer interface {
+	// C is the channel on which the time is sent when the timer fires
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing, the result is false if the
+	// timer has already fired or has been stopped
+	Stop() bool
+
+	// Reset changes the timer to fire after the duration
+	Reset(d time.Duration) bool
+}
+
+// SystemClock is the clock of the operating system, it is used by default
+var SystemClock Clock = systemClock{}
+
+// systemClock is a Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// systemTimer is a Timer backed by time.Timer
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// clockOrSystem returns the clock, or the system clock if it is nil
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return SystemClock
+	}
+
+	return c
+}
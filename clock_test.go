@@ -0,0 +1,615 @@
+package hooks_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	hooks "logrus-hooks.git"
+	"logrus-hooks.git/hookstest"
+)
+
+// newFakeClock creates a fake clock that starts at a fixed time
+func newFakeClock() *hookstest.FakeClock {
+	return hookstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// newEntry creates a log entry of the standard logger
+func newEntry(level logrus.Level, msg string, fields logrus.Fields) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(fields)
+	entry.Level = level
+	entry.Message = msg
+
+	return entry
+}
+
+func TestSystemClock(t *testing.T) {
+	clock := hooks.SystemClock
+
+	start := clock.Now()
+	clock.Sleep(time.Millisecond)
+	<-clock.After(time.Millisecond)
+
+	timer := clock.NewTimer(time.Hour)
+	if !timer.Reset(time.Millisecond) {
+		t.Errorf("reset of an active timer returned false")
+	}
+	<-timer.C()
+	if timer.Stop() {
+		t.Errorf("stop of a fired timer returned true")
+	}
+
+	if elapsed := clock.Now().Sub(start); elapsed < 3*time.Millisecond {
+		t.Errorf("system clock did not move: %s", elapsed)
+	}
+}
+
+func TestClock_Retry(t *testing.T) {
+	clock := newFakeClock()
+	clock.SetAutoAdvance(true)
+
+	hook := hooks.RetryHook(hookstest.NewScriptedHook(nil, hookstest.FailTimes(10, nil)...), time.Second,
+		hooks.Retries(3),
+		hooks.FactorPct(100),
+		hooks.JitterPct(0),
+		hooks.RetryClock(clock),
+	)
+
+	err := hook.Fire(nil)
+
+	// the pauses are 1s, 1s and 2s, without any real waiting
+	var retryErr *hooks.RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("unexpected error after all retries: %v", err)
+	}
+	if retryErr.Elapsed != 4*time.Second {
+		t.Errorf("wrong time of the retries: expected=4s, found=%s", retryErr.Elapsed)
+	}
+}
+
+func TestClock_RetryBlocking(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.RetryHook(hookstest.NewScriptedHook(nil, hookstest.FailTimes(1, nil)...), time.Minute, hooks.RetryClock(clock))
+
+	done := make(chan error)
+	go func() {
+		done <- hook.Fire(nil)
+	}()
+
+	// the hook waits for the clock before the retry
+	clock.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatalf("retry did not wait for the clock")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	if err := <-done; err != nil {
+		t.Errorf("retry failed: %s", err)
+	}
+}
+
+func TestClock_RetryFailure(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		clock := newFakeClock()
+		clock.SetAutoAdvance(true)
+
+		hook := hooks.RetryHook(
+			hookstest.NewScriptedHook(nil, hookstest.FailTimes(i+1, nil)...),
+			time.Microsecond,
+			hooks.Retries(i),
+			hooks.RetryClock(clock),
+		)
+
+		if err := hook.Fire(nil); err == nil {
+			t.Errorf("success with %d retries", i)
+		}
+	}
+}
+
+func TestClock_RetrySuccess(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		clock := newFakeClock()
+		clock.SetAutoAdvance(true)
+
+		hook := hooks.RetryHook(
+			hookstest.NewScriptedHook(nil, hookstest.FailTimes(i, nil)...),
+			time.Microsecond,
+			hooks.Retries(i),
+			hooks.RetryClock(clock),
+		)
+
+		if err := hook.Fire(nil); err != nil {
+			t.Errorf("failed with %d retries", i)
+		}
+	}
+}
+
+func TestClock_RetryReconfigure(t *testing.T) {
+	clock := newFakeClock()
+	clock.SetAutoAdvance(true)
+
+	hook := hooks.RetryHook(
+		hookstest.NewScriptedHook(nil, hookstest.FailTimes(5, nil)...),
+		time.Microsecond,
+		hooks.Retries(1),
+		hooks.RetryClock(clock),
+	)
+
+	if err := hook.Fire(nil); err == nil {
+		t.Fatalf("success with 1 retry")
+	}
+
+	theHook, ok := hook.(hooks.Reconfigurable[hooks.RetryOption])
+	if !ok {
+		t.Fatalf("retry hook can not be reconfigured: %v", hook)
+	}
+	if err := theHook.Reconfigure(hooks.Retries(5), hooks.RetryDelay(2*time.Microsecond)); err != nil {
+		t.Fatalf("failed to reconfigure the retry hook: %s", err)
+	}
+
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("failed with 5 retries after the reconfiguration: %s", err)
+	}
+}
+
+func TestClock_RateLimit(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.RateLimitHook(hookstest.NewRecorder(),
+		hooks.PerSecond(1),
+		hooks.Burst(2),
+		hooks.RateLimitClock(clock),
+	)
+
+	for round, expected := range []int{2, 0, 1, 2} {
+		permitted := 0
+		for i := 0; i < 5; i++ {
+			if hook.Fire(nil) == nil {
+				permitted++
+			}
+		}
+		if permitted != expected {
+			t.Errorf("wrong number of permitted messages at round [%d]: expected=%d, found=%d",
+				round, expected, permitted)
+		}
+
+		clock.Advance(time.Duration(round) * time.Second)
+	}
+}
+
+func TestClock_RateLimitRates(t *testing.T) {
+	for _, ratePerSecond := range []int{1, 10, 25, 50, 100} {
+		clock := newFakeClock()
+		hook := hooks.RateLimitHook(hookstest.NewRecorder(),
+			hooks.PerSecond(ratePerSecond),
+			hooks.RateLimitClock(clock),
+		)
+
+		t.Run(fmt.Sprintf("%d-per-sec", ratePerSecond), func(t *testing.T) {
+			for i := 0; i < ratePerSecond; i++ {
+				if err := hook.Fire(nil); err != nil {
+					t.Fatalf("rate limited too early after %d times: %s", i, err)
+				}
+				if i < (ratePerSecond - 1) {
+					clock.Advance(time.Second / time.Duration(ratePerSecond))
+				}
+			}
+
+			if hook.Fire(nil) == nil {
+				t.Fatalf("hook was not limited after %d times", ratePerSecond)
+			}
+		})
+	}
+}
+
+func TestClock_RateLimitDelay(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.RateLimitHook(hookstest.NewRecorder(), hooks.PerSecond(10), hooks.RateLimitClock(clock))
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message was rate limited: %s", err)
+	}
+
+	// the dropped messages report the time until the next one fits
+	for _, expected := range []time.Duration{100 * time.Millisecond, 60 * time.Millisecond} {
+		var limitErr *hooks.RateLimitError
+		if err := hook.Fire(nil); !errors.As(err, &limitErr) {
+			t.Fatalf("message was not rate limited: %v", err)
+		}
+		if limitErr.Delay != expected {
+			t.Errorf("wrong delay of the rate limit: expected=%s, found=%s", expected, limitErr.Delay)
+		}
+		clock.Advance(40 * time.Millisecond)
+	}
+
+	// the reports did not take any tokens
+	clock.Advance(20 * time.Millisecond)
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("message was rate limited after the delay: %s", err)
+	}
+}
+
+func TestClock_RateLimitSummarize(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.RateLimitHook(sink,
+		hooks.PerSecond(20),
+		hooks.Burst(1),
+		hooks.Summarize(),
+		hooks.RateLimitClock(clock),
+	)
+
+	permitted := 0
+	for i := 0; i < 5; i++ {
+		if hook.Fire(newEntry(logrus.WarnLevel, fmt.Sprintf("test message: %d", i), nil)) == nil {
+			permitted++
+		}
+	}
+	if permitted != 1 {
+		t.Fatalf("unexpected number of permitted messages: %d", permitted)
+	}
+
+	// wait for the window to open again
+	clock.Advance(100 * time.Millisecond)
+	if err := hook.Fire(newEntry(logrus.InfoLevel, "test message: after the pause", nil)); err != nil {
+		t.Fatalf("message was rate limited after the pause: %s", err)
+	}
+
+	sink.AssertMessages(t,
+		"test message: 0",
+		"suppressed 4 entries (4 warning) in the last 100ms",
+		"test message: after the pause",
+	)
+	sink.AssertField(t, 1, "suppressed", 4)
+}
+
+func TestClock_RateLimitWait(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.RateLimitHook(hookstest.NewRecorder(),
+		hooks.PerSecond(1),
+		hooks.Wait(time.Minute),
+		hooks.RateLimitClock(clock),
+	)
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message was rate limited: %s", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- hook.Fire(nil)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("message was dropped instead of delayed: %s", err)
+	}
+
+	// the wait for a message over the maximum wait fails right away
+	hook = hooks.RateLimitHook(hookstest.NewRecorder(),
+		hooks.PerSecond(1),
+		hooks.Wait(100*time.Millisecond),
+		hooks.RateLimitClock(clock),
+	)
+	_ = hook.Fire(nil)
+	if err := hook.Fire(nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error of a long wait: %v", err)
+	}
+	if n := clock.Pending(); n != 0 {
+		t.Errorf("hook is waiting for the clock after it gave up: %d timers", n)
+	}
+}
+
+func TestClock_RateLimitWaitRates(t *testing.T) {
+	for _, ratePerSecond := range []int{10, 25, 50, 100} {
+		clock := newFakeClock()
+		hook := hooks.RateLimitHook(hookstest.NewRecorder(),
+			hooks.PerSecond(ratePerSecond),
+			hooks.Wait(time.Second),
+			hooks.RateLimitClock(clock),
+		)
+
+		t.Run(fmt.Sprintf("%d-per-sec", ratePerSecond), func(t *testing.T) {
+			nMessages := 5
+			interval := time.Second / time.Duration(ratePerSecond)
+
+			start := clock.Now()
+			for i := 0; i < nMessages; i++ {
+				done := make(chan error, 1)
+				go func() {
+					done <- hook.Fire(nil)
+				}()
+
+				// every message after the first one waits for the clock
+				if i > 0 {
+					clock.BlockUntil(1)
+					select {
+					case err := <-done:
+						t.Fatalf("message did not wait at round [%d]: %v", i, err)
+					default:
+					}
+					clock.Advance(interval)
+				}
+
+				if err := <-done; err != nil {
+					t.Fatalf("message was dropped instead of delayed at round [%d]: %s", i, err)
+				}
+			}
+
+			if elapsed := clock.Now().Sub(start); elapsed != time.Duration(nMessages-1)*interval {
+				t.Errorf("wrong delay of the messages: expected=%s, found=%s",
+					time.Duration(nMessages-1)*interval, elapsed)
+			}
+		})
+	}
+}
+
+func TestClock_RateLimitReconfigure(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.RateLimitHook(hookstest.NewRecorder(),
+		hooks.PerSecond(1),
+		hooks.Burst(1),
+		hooks.RateLimitClock(clock),
+	)
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message was rate limited: %s", err)
+	}
+	if hook.Fire(nil) == nil {
+		t.Fatalf("hook was not limited after the burst")
+	}
+
+	theHook, ok := hook.(hooks.Reconfigurable[hooks.RateLimitOption])
+	if !ok {
+		t.Fatalf("rate limit hook can not be reconfigured: %v", hook)
+	}
+	if err := theHook.Reconfigure(hooks.PerSecond(1000), hooks.Burst(10), hooks.Exempt(logrus.ErrorLevel)); err != nil {
+		t.Fatalf("failed to reconfigure the rate limit hook: %s", err)
+	}
+
+	// wait for the new limit to refill the burst
+	clock.Advance(20 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		if err := hook.Fire(nil); err != nil {
+			t.Fatalf("rate limited too early after the reconfiguration at round [%d]: %s", i, err)
+		}
+	}
+
+	entry := newEntry(logrus.ErrorLevel, "exempt message", nil)
+	for i := 0; i < 100; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("exempt message was rate limited at round [%d]: %s", i, err)
+		}
+	}
+}
+
+func TestClock_KeyedRateLimitSummarize(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.KeyedRateLimitHook(sink,
+		hooks.KeyFields("tenant"),
+		hooks.PerSecond(20),
+		hooks.Burst(1),
+		hooks.Summarize(),
+		hooks.RateLimitClock(clock),
+	)
+
+	for _, tenant := range []string{"acme", "globex"} {
+		entry := newEntry(logrus.InfoLevel, "first message of "+tenant, logrus.Fields{"tenant": tenant})
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("first message of %s was rate limited: %s", tenant, err)
+		}
+		if hook.Fire(entry) == nil {
+			t.Fatalf("second message of %s was not rate limited", tenant)
+		}
+	}
+
+	// wait for the window to open again
+	clock.Advance(100 * time.Millisecond)
+
+	entry := newEntry(logrus.InfoLevel, "message after the pause", logrus.Fields{"tenant": "acme"})
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("message was rate limited after the pause: %s", err)
+	}
+
+	// only the key of the message reports its dropped messages
+	if sink.AssertCount(t, 4) {
+		sink.AssertField(t, 2, "suppressed", 1)
+		sink.AssertField(t, 2, "key", "acme")
+		sink.AssertNoField(t, 3, "suppressed")
+	}
+}
+
+func TestClock_KeyedRateLimitReconfigure(t *testing.T) {
+	clock := newFakeClock()
+	hook := hooks.KeyedRateLimitHook(hookstest.NewRecorder(),
+		hooks.KeyMessage(),
+		hooks.PerSecond(1),
+		hooks.Burst(1),
+		hooks.RateLimitClock(clock),
+	)
+
+	entry := newEntry(logrus.InfoLevel, "test message", nil)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("first message was rate limited: %s", err)
+	}
+	if hook.Fire(entry) == nil {
+		t.Fatalf("hook was not limited after the burst")
+	}
+
+	theHook, ok := hook.(hooks.Reconfigurable[hooks.RateLimitOption])
+	if !ok {
+		t.Fatalf("keyed rate limit hook can not be reconfigured: %v", hook)
+	}
+	if err := theHook.Reconfigure(hooks.PerSecond(1000), hooks.Burst(10), hooks.GlobalLimit(1, 3)); err != nil {
+		t.Fatalf("failed to reconfigure the keyed rate limit hook: %s", err)
+	}
+
+	// wait for the new limit to refill the burst
+	clock.Advance(20 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("rate limited too early after the reconfiguration at round [%d]: %s", i, err)
+		}
+	}
+	if hook.Fire(entry) == nil {
+		t.Fatalf("hook was not limited by the new global limit")
+	}
+}
+
+func TestClock_Dedup(t *testing.T) {
+	clock := newFakeClock()
+	sink := hookstest.NewRecorder()
+	hook := hooks.DedupHook(sink, hooks.DedupWindow(time.Minute), hooks.DedupClock(clock))
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "repeated message"
+	for i := 0; i < 3; i++ {
+		_ = hook.Fire(entry)
+		clock.Advance(10 * time.Second)
+	}
+	clock.Advance(time.Minute)
+	_ = hook.Fire(entry)
+
+	sink.AssertMessages(t,
+		"repeated message",
+		"repeated message (repeated 2 times in the last 1m30s)",
+		"repeated message",
+	)
+}
+
+func TestClock_DedupTimer(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.DedupHook(sink, hooks.DedupWindow(time.Minute), hooks.DedupClock(clock))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the hook: %s", err)
+	}
+
+	for _, msg := range []string{"a", "a", "a"} {
+		if err := hook.Fire(newEntry(logrus.WarnLevel, msg, nil)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+	clock.Advance(30 * time.Second)
+	for _, msg := range []string{"b", "b"} {
+		if err := hook.Fire(newEntry(logrus.WarnLevel, msg, nil)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	// the timer closes the window of "a" without more messages, and then
+	// waits for the window of "b"
+	clock.Advance(30 * time.Second)
+	clock.BlockUntil(1)
+	if sink.AssertMessages(t, "a", "b", "a (repeated 2 times in the last 1m0s)") {
+		sink.AssertField(t, 2, "repeated", 2)
+	}
+
+	// the stop reports the windows that are still open
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the hook: %s", err)
+	}
+	if hook.IsRunning() {
+		t.Errorf("hook is running after the stop")
+	}
+	if sink.AssertCount(t, 4) {
+		sink.AssertField(t, 3, "repeated", 1)
+	}
+}
+
+func TestClock_DedupWindow(t *testing.T) {
+	for _, repeats := range []int{1, 2, 10, 100} {
+		sink := hookstest.NewRecorder()
+		clock := newFakeClock()
+		hook := hooks.DedupHook(sink, hooks.DedupWindow(20*time.Millisecond), hooks.DedupClock(clock))
+
+		t.Run(fmt.Sprintf("%d-times", repeats), func(t *testing.T) {
+			entry := newEntry(logrus.WarnLevel, "test message", nil)
+			for i := 0; i < repeats; i++ {
+				if err := hook.Fire(entry); err != nil {
+					t.Fatalf("fire failed at round [%d]: %s", i, err)
+				}
+			}
+			if !sink.AssertCount(t, 1) {
+				t.FailNow()
+			}
+
+			// the next message after the window closes the window
+			clock.Advance(30 * time.Millisecond)
+			if err := hook.Fire(newEntry(logrus.WarnLevel, "another message", nil)); err != nil {
+				t.Fatalf("fire failed after the window: %s", err)
+			}
+
+			if repeats == 1 {
+				// a single message has no repeats to report
+				sink.AssertMessages(t, "test message", "another message")
+				return
+			}
+			if sink.AssertCount(t, 3) {
+				sink.AssertField(t, 1, "repeated", repeats-1)
+				sink.AssertLevels(t, logrus.WarnLevel, logrus.WarnLevel, logrus.WarnLevel)
+			}
+		})
+	}
+}
+
+func TestClock_SamplingTick(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.SamplingHook(sink, hooks.First(2), hooks.Tick(20*time.Millisecond), hooks.SampleClock(clock))
+
+	entry := newEntry(logrus.InfoLevel, "test message", nil)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 10; i++ {
+			if err := hook.Fire(entry); err != nil {
+				t.Fatalf("fire failed at round [%d]: %s", i, err)
+			}
+		}
+		clock.Advance(30 * time.Millisecond)
+	}
+
+	// the first two messages of every tick go through
+	sink.AssertCount(t, 6)
+}
+
+func TestClock_BufferEviction(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	clock := newFakeClock()
+	hook := hooks.BufferOnErrorHook(sink, hooks.KeyFields("request_id"),
+		hooks.BufferMaxKeys(2),
+		hooks.BufferTTL(20*time.Millisecond),
+		hooks.BufferClock(clock),
+	)
+
+	fire := func(level logrus.Level, msg, requestID string) {
+		t.Helper()
+		if err := hook.Fire(newEntry(level, msg, logrus.Fields{"request_id": requestID})); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		fire(logrus.DebugLevel, key+"1", key)
+	}
+
+	// the buffer of the least recently used key was dropped
+	fire(logrus.ErrorLevel, "a2", "a")
+	sink.AssertMessages(t, "a2")
+
+	// the buffers of the keys that were not used are dropped
+	clock.Advance(40 * time.Millisecond)
+	fire(logrus.ErrorLevel, "b2", "b")
+	sink.AssertMessages(t, "a2", "b2")
+
+	state := hook.(hooks.Inspector).Inspect()
+	if state["keys"] != 0 || state["dropped_keys"] != uint64(3) {
+		t.Errorf("wrong state of the buffers: %v", state)
+	}
+}
@@ -0,0 +1,665 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Registry holds the named sinks that the pipelines created from
+// configuration send their messages to
+//
+// The registry also keeps the named pipelines, so that they can be inspected
+// by the debug handler.
+type Registry struct {
+	sync.Mutex
+	sinks     map[string]logrus.Hook
+	pipelines map[string]*Pipeline
+	metrics   Metrics
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		sinks:     make(map[string]logrus.Hook),
+		pipelines: make(map[string]*Pipeline),
+	}
+}
+
+// Register adds a sink to the registry, replacing any sink with the same name
+func (r *Registry) Register(name string, sink logrus.Hook) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.sinks[name] = sink
+}
+
+// SetMetrics sets the metrics that receive the events of the stages of the
+// pipelines created from configuration, under the names of the stages
+func (r *Registry) SetMetrics(m Metrics) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.metrics = m
+}
+
+// Sink looks up a sink by name
+func (r *Registry) Sink(name string) (logrus.Hook, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	sink, found := r.sinks[name]
+	return sink, found
+}
+
+// AddPipeline adds a pipeline to the registry, replacing any pipeline with
+// the same name
+func (r *Registry) AddPipeline(p *Pipeline) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.pipelines[p.Name()] = p
+}
+
+// Pipeline looks up a pipeline by name
+func (r *Registry) Pipeline(name string) (*Pipeline, bool) {
+	r.Lock()
+	defer r.Unlock()
+
+	p, found := r.pipelines[name]
+	return p, found
+}
+
+// Pipelines lists the pipelines of the registry, sorted by name
+func (r *Registry) Pipelines() []*Pipeline {
+	r.Lock()
+	defer r.Unlock()
+
+	pipelines := make([]*Pipeline, 0, len(r.pipelines))
+	for _, p := range r.pipelines {
+		pipelines = append(pipelines, p)
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].Name() < pipelines[j].Name()
+	})
+
+	return pipelines
+}
+
+// ConfigError is a problem with one value of the configuration of a pipeline
+type ConfigError struct {
+	// Path locates the value in the configuration, like "stages[0].retries"
+	Path string
+
+	// Problem describes what is wrong with the value
+	Problem string
+}
+
+func (e *ConfigError) Error() string {
+	return e.Path + ": " + e.Problem
+}
+
+// Load creates a pipeline from a YAML or JSON configuration
+//
+// The configuration names the sink from the registry and lists the stages
+// in the order in which they receive the messages:
+//
+//	name: audit
+//	sink: syslog
+//	stages:
+//	  - type: rate_limit
+//	    per_second: 10
+//	    burst: 20
+//	  - type: async
+//	    senders: 4
+//	  - type: retry
+//	    delay: 100ms
+//	    retries: 3
+//
+// The stage types are retry, rate_limit, keyed_rate_limit, async, dedup,
+// sampling and level_filter, their keys match the names of the functional options. All
+// problems with the configuration are reported together, each one as a
+// *ConfigError with the path to the offending value. A pipeline with a
+// name is added to the registry.
+func Load(config []byte, registry *Registry) (*Pipeline, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(config, &doc); err != nil {
+		return nil, fmt.Errorf("invalid pipeline configuration: %w", err)
+	}
+
+	var errs []error
+	root := newConfigObject("", doc, &errs)
+
+	name := root.string("name")
+	sinkName := root.string("sink")
+	stageList := root.list("stages")
+	root.done()
+
+	var (
+		sink    logrus.Hook
+		found   bool
+		metrics Metrics
+	)
+	if registry != nil {
+		sink, found = registry.Sink(sinkName)
+
+		registry.Lock()
+		metrics = registry.metrics
+		registry.Unlock()
+	}
+	switch {
+	case sinkName == "":
+		root.fail("sink", "missing sink")
+	case !found:
+		root.fail("sink", fmt.Sprintf("unknown sink %q", sinkName))
+	}
+
+	// the stages are created from the last to the first
+	stages := make([]Stage, len(stageList)+1)
+	stages[len(stageList)] = Stage{Name: sinkName, Type: "sink", Hook: sink}
+
+	// the problems are reported in the order of the stages
+	stageErrs := make([][]error, len(stageList))
+
+	next := sink
+	for i := len(stageList) - 1; i >= 0; i-- {
+		obj := newConfigObject(fmt.Sprintf("stages[%d]", i), stageList[i], &stageErrs[i])
+		stages[i] = obj.stage(next, metrics)
+		next = stages[i].Hook
+	}
+	for _, e := range stageErrs {
+		errs = append(errs, e...)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// the hooks were created for the pipeline, so it can keep their errors
+	pipeline := NewPipeline(name, stages...).CaptureAsyncErrors()
+	if name != "" {
+		registry.AddPipeline(pipeline)
+	}
+
+	return pipeline, nil
+}
+
+// stage creates the hook of a stage that sends its messages to the next hook
+func (obj *configObject) stage(next logrus.Hook, metrics Metrics) Stage {
+	stage := Stage{
+		Type: obj.string("type"),
+		Name: obj.string("name"),
+	}
+	if stage.Name == "" {
+		stage.Name = stage.Type
+	}
+
+	switch stage.Type {
+	case "retry":
+		opts := append(obj.retryOptions(), RetryMetrics(metrics, stage.Name))
+		stage.Hook = RetryHook(next, obj.duration("delay"), opts...)
+	case "rate_limit":
+		opts := append(obj.rateLimitOptions(), RateLimitMetrics(metrics, stage.Name))
+		stage.Hook = RateLimitHook(next, opts...)
+	case "keyed_rate_limit":
+		key := obj.keyFunc()
+		opts := append(obj.rateLimitOptions(), obj.keyedOptions()...)
+		opts = append(opts, RateLimitMetrics(metrics, stage.Name))
+		stage.Hook = KeyedRateLimitHook(next, key, opts...)
+	case "async":
+		opts := append(obj.asyncOptions(), AsyncMetrics(metrics, stage.Name))
+		stage.Hook = AsyncHook(next, opts...)
+	case "dedup":
+		stage.Hook = DedupHook(next, obj.dedupOptions()...)
+	case "sampling":
+		stage.Hook = SamplingHook(next, obj.sampleOptions()...)
+	case "level_filter":
+		stage.Hook = obj.levelFilter(next)
+	case "":
+		obj.fail("type", "missing stage type")
+	default:
+		obj.fail("type", fmt.Sprintf("unknown stage type %q", stage.Type))
+	}
+
+	obj.done()
+
+	return stage
+}
+
+func (obj *configObject) retryOptions() []RetryOption {
+	var opts []RetryOption
+	if n, found := obj.int("retries", 0); found {
+		opts = append(opts, Retries(n))
+	}
+	if n, found := obj.int("factor_pct", 0); found {
+		opts = append(opts, FactorPct(int64(n)))
+	}
+	if n, found := obj.int("jitter_pct", 0); found {
+		opts = append(opts, JitterPct(int64(n)))
+	}
+
+	return opts
+}
+
+func (obj *configObject) rateLimitOptions() []RateLimitOption {
+	var opts []RateLimitOption
+	if n, found := obj.int("per_second", 1); found {
+		opts = append(opts, PerSecond(n))
+	}
+	if n, found := obj.int("burst", 1); found {
+		opts = append(opts, Burst(n))
+	}
+	if d, found := obj.optionalDuration("wait"); found {
+		// a zero maximum wait would block the callers without a bound
+		if d < time.Millisecond {
+			obj.fail("wait", fmt.Sprintf("must be at least 1ms, found %s", d))
+		} else {
+			opts = append(opts, Wait(d))
+		}
+	}
+	if obj.bool("summarize") {
+		opts = append(opts, Summarize())
+	}
+	for i, item := range obj.list("level_limits") {
+		limit := newConfigObject(fmt.Sprintf("%s.level_limits[%d]", obj.path, i), item, obj.errs)
+		perSecond, burst := limit.limit()
+		opts = append(opts, LevelLimit(perSecond, burst, limit.levels("levels")...))
+		limit.done()
+	}
+	if levels := obj.levels("exempt"); len(levels) > 0 {
+		opts = append(opts, Exempt(levels...))
+	}
+
+	return opts
+}
+
+func (obj *configObject) keyedOptions() []RateLimitOption {
+	var opts []RateLimitOption
+	if n, found := obj.int("max_keys", 0); found {
+		opts = append(opts, MaxKeys(n))
+	}
+	if d, found := obj.optionalDuration("key_ttl"); found {
+		opts = append(opts, KeyTTL(d))
+	}
+	if global := obj.object("global"); global != nil {
+		perSecond, burst := global.limit()
+		opts = append(opts, GlobalLimit(perSecond, burst))
+		global.done()
+	}
+
+	return opts
+}
+
+// limit reads the rate and the burst of a limit, the burst is the default
+// burst when it is missing
+func (obj *configObject) limit() (int, int) {
+	perSecond, found := obj.int("per_second", 1)
+	if _, present := obj.values["per_second"]; !found && !present {
+		obj.fail("per_second", "missing rate")
+	}
+
+	burst, found := obj.int("burst", 1)
+	if !found {
+		burst = defaultBurst
+	}
+
+	return perSecond, burst
+}
+
+func (obj *configObject) keyFunc() KeyFunc {
+	fields := obj.strings("key_fields")
+	switch key := obj.string("key"); {
+	case len(fields) > 0 && key != "":
+		obj.fail("key", "key and key_fields are mutually exclusive")
+	case len(fields) > 0:
+		return KeyFields(fields...)
+	case key == "message":
+		return KeyMessage()
+	case key == "caller":
+		return KeyCaller()
+	case key == "":
+		obj.fail("key", "missing key or key_fields")
+	default:
+		obj.fail("key", fmt.Sprintf("unknown key %q, expected message or caller", key))
+	}
+
+	return KeyMessage()
+}
+
+func (obj *configObject) asyncOptions() []AsyncOption {
+	var opts []AsyncOption
+	if n, found := obj.int("senders", 1); found {
+		opts = append(opts, Senders(uint32(n)))
+	}
+	if n, found := obj.int("boost_senders", 0); found {
+		opts = append(opts, BoostSenders(uint32(n)))
+	}
+	if n, found := obj.int("buffer_len", 1); found {
+		opts = append(opts, BufferLen(uint32(n)))
+	}
+
+	return opts
+}
+
+func (obj *configObject) dedupOptions() []DedupOption {
+	var opts []DedupOption
+	if d, found := obj.optionalDuration("window"); found {
+		opts = append(opts, DedupWindow(d))
+	}
+	if fields := obj.strings("fields"); len(fields) > 0 {
+		opts = append(opts, DedupFields(fields...))
+	}
+	if n, found := obj.int("max_keys", 1); found {
+		opts = append(opts, DedupMaxKeys(n))
+	}
+
+	return opts
+}
+
+func (obj *configObject) sampleOptions() []SampleOption {
+	var opts []SampleOption
+	if d, found := obj.optionalDuration("tick"); found {
+		opts = append(opts, Tick(d))
+	}
+	if n, found := obj.int("first", 0); found {
+		opts = append(opts, First(n))
+	}
+	if n, found := obj.int("thereafter", 0); found {
+		opts = append(opts, Thereafter(n))
+	}
+	if rate, found := obj.rate("rate"); found {
+		opts = append(opts, SampleRate(rate))
+	}
+	if rates := obj.object("level_rates"); rates != nil {
+		for _, key := range rates.keys() {
+			rate, found := rates.rate(key)
+			level, err := logrus.ParseLevel(key)
+			if err != nil {
+				rates.fail(key, "unknown log level")
+				continue
+			}
+			if found {
+				opts = append(opts, SampleRate(rate, level))
+			}
+		}
+		rates.done()
+	}
+	if field := obj.string("by_field"); field != "" {
+		opts = append(opts, SampleByField(field))
+	}
+
+	return opts
+}
+
+func (obj *configObject) levelFilter(next logrus.Hook) logrus.Hook {
+	levels := obj.levels("levels")
+	name := obj.string("min_level")
+
+	switch _, found := obj.values["levels"]; {
+	case found && name != "":
+		obj.fail("min_level", "levels and min_level are mutually exclusive")
+	case name != "":
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			obj.fail("min_level", fmt.Sprintf("unknown log level %q", name))
+		}
+		return MinLevel(next, level)
+	case !found:
+		obj.fail("levels", "missing levels or min_level")
+	}
+
+	return LevelFilterHook(next, levels...)
+}
+
+// configObject reads the values of a configuration object and keeps track
+// of the problems with them
+type configObject struct {
+	path   string
+	values map[string]interface{}
+	used   map[string]bool
+	errs   *[]error
+}
+
+// newConfigObject wraps a decoded configuration value that must be an object
+func newConfigObject(path string, value interface{}, errs *[]error) *configObject {
+	obj := &configObject{
+		path: path,
+		used: make(map[string]bool),
+		errs: errs,
+	}
+
+	switch values := value.(type) {
+	case map[string]interface{}:
+		obj.values = values
+	case nil:
+		obj.values = map[string]interface{}{}
+	default:
+		obj.values = map[string]interface{}{}
+		obj.fail("", "expected an object")
+	}
+
+	return obj
+}
+
+// fail records a problem with the value of a key
+func (obj *configObject) fail(key, problem string) {
+	path := obj.path
+	switch {
+	case path == "":
+		path = key
+	case key != "":
+		path += "." + key
+	}
+	if path == "" {
+		path = "."
+	}
+
+	*obj.errs = append(*obj.errs, &ConfigError{Path: path, Problem: problem})
+}
+
+// lookup finds the value of a key and marks the key as known
+func (obj *configObject) lookup(key string) (interface{}, bool) {
+	obj.used[key] = true
+	value, found := obj.values[key]
+
+	return value, found && value != nil
+}
+
+// done reports the keys of the object that were never looked up
+func (obj *configObject) done() {
+	for _, key := range obj.keys() {
+		if !obj.used[key] {
+			obj.fail(key, "unknown key")
+		}
+	}
+}
+
+// keys lists the keys of the object in sorted order
+func (obj *configObject) keys() []string {
+	keys := make([]string, 0, len(obj.values))
+	for key := range obj.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (obj *configObject) string(key string) string {
+	value, found := obj.lookup(key)
+	if !found {
+		return ""
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		obj.fail(key, "expected a string")
+	}
+
+	return s
+}
+
+func (obj *configObject) strings(key string) []string {
+	list := obj.list(key)
+	values := make([]string, 0, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			obj.fail(fmt.Sprintf("%s[%d]", key, i), "expected a string")
+			continue
+		}
+		values = append(values, s)
+	}
+
+	return values
+}
+
+func (obj *configObject) bool(key string) bool {
+	value, found := obj.lookup(key)
+	if !found {
+		return false
+	}
+
+	b, ok := value.(bool)
+	if !ok {
+		obj.fail(key, "expected true or false")
+	}
+
+	return b
+}
+
+// int reads an integer that can not be less than min
+func (obj *configObject) int(key string, min int) (int, bool) {
+	value, found := obj.lookup(key)
+	if !found {
+		return 0, false
+	}
+
+	n, ok := value.(int)
+	switch {
+	case !ok:
+		obj.fail(key, "expected an integer")
+		return 0, false
+	case n < min && min == 0:
+		obj.fail(key, fmt.Sprintf("must not be negative, found %d", n))
+		return 0, false
+	case n < min:
+		obj.fail(key, fmt.Sprintf("must be at least %d, found %d", min, n))
+		return 0, false
+	}
+
+	return n, true
+}
+
+// rate reads a number between 0 and 1
+func (obj *configObject) rate(key string) (float64, bool) {
+	value, found := obj.lookup(key)
+	if !found {
+		return 0, false
+	}
+
+	var rate float64
+	switch v := value.(type) {
+	case int:
+		rate = float64(v)
+	case float64:
+		rate = v
+	default:
+		obj.fail(key, "expected a number")
+		return 0, false
+	}
+
+	if rate < 0 || rate > 1 {
+		obj.fail(key, fmt.Sprintf("must be between 0 and 1, found %v", rate))
+		return 0, false
+	}
+
+	return rate, true
+}
+
+// duration reads a duration like "100ms" that must be present
+func (obj *configObject) duration(key string) time.Duration {
+	d, found := obj.optionalDuration(key)
+	if !found {
+		if _, present := obj.values[key]; !present {
+			obj.fail(key, "missing duration")
+		}
+	}
+
+	return d
+}
+
+// optionalDuration reads a duration like "100ms" that may be missing
+func (obj *configObject) optionalDuration(key string) (time.Duration, bool) {
+	value, found := obj.lookup(key)
+	if !found {
+		return 0, false
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		obj.fail(key, `expected a duration like "100ms"`)
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(s)
+	switch {
+	case err != nil:
+		obj.fail(key, fmt.Sprintf("invalid duration %q", s))
+		return 0, false
+	case d < 0:
+		obj.fail(key, fmt.Sprintf("must not be negative, found %s", s))
+		return 0, false
+	}
+
+	return d, true
+}
+
+func (obj *configObject) levels(key string) []logrus.Level {
+	names := obj.strings(key)
+	levels := make([]logrus.Level, 0, len(names))
+	for i, name := range names {
+		level, err := logrus.ParseLevel(name)
+		if err != nil {
+			obj.fail(fmt.Sprintf("%s[%d]", key, i), fmt.Sprintf("unknown log level %q", name))
+			continue
+		}
+		levels = append(levels, level)
+	}
+
+	return levels
+}
+
+func (obj *configObject) list(key string) []interface{} {
+	value, found := obj.lookup(key)
+	if !found {
+		return nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		obj.fail(key, "expected a list")
+	}
+
+	return list
+}
+
+// object reads a nested object, nil is returned when the key is missing
+func (obj *configObject) object(key string) *configObject {
+	value, found := obj.lookup(key)
+	if !found {
+		return nil
+	}
+
+	path := key
+	if obj.path != "" {
+		path = obj.path + "." + key
+	}
+
+	return newConfigObject(path, value, obj.errs)
+}
@@ -0,0 +1,279 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoad(t *testing.T) {
+	testData := []struct {
+		format string
+		config string
+	}{
+		{"yaml", `
+name: audit
+sink: recorder
+stages:
+  - type: rate_limit
+    name: limits
+    per_second: 100
+    burst: 10
+    summarize: true
+    level_limits:
+      - levels: [debug, trace]
+        per_second: 1000
+        burst: 100
+    exempt: [panic, fatal, error]
+  - type: keyed_rate_limit
+    key_fields: [tenant]
+    per_second: 100
+    burst: 10
+    max_keys: 100
+    key_ttl: 1m
+    global:
+      per_second: 1000
+      burst: 100
+  - type: sampling
+    first: 100
+    thereafter: 10
+    level_rates:
+      error: 1
+  - type: dedup
+    window: 1s
+    fields: [user]
+  - type: async
+    senders: 2
+    buffer_len: 16
+  - type: retry
+    delay: 1ms
+    retries: 3
+    factor_pct: 50
+    jitter_pct: 10
+`},
+		{"json", `{
+  "name": "audit",
+  "sink": "recorder",
+  "stages": [
+    {"type": "rate_limit", "name": "limits", "per_second": 100, "burst": 10},
+    {"type": "keyed_rate_limit", "key": "message", "per_second": 100},
+    {"type": "sampling", "rate": 1},
+    {"type": "dedup", "max_keys": 10},
+    {"type": "async", "senders": 2, "boost_senders": 2},
+    {"type": "retry", "delay": "1ms", "retries": 3}
+  ]
+}`},
+	}
+
+	expectedTypes := []string{
+		"rate_limit", "keyed_rate_limit", "sampling", "dedup", "async", "retry", "sink",
+	}
+
+	for _, td := range testData {
+		t.Run(td.format, func(t *testing.T) {
+			var mockHook mockRecordingHook
+
+			registry := NewRegistry()
+			registry.Register("recorder", &mockHook)
+
+			pipeline, err := Load([]byte(td.config), registry)
+			if err != nil {
+				t.Fatalf("failed to load the pipeline: %s", err)
+			}
+
+			if pipeline.Name() != "audit" {
+				t.Errorf("wrong name of the pipeline: %s", pipeline.Name())
+			}
+
+			stages := pipeline.Stages()
+			if len(stages) != len(expectedTypes) {
+				t.Fatalf("wrong number of stages: expected=%d, found=%d", len(expectedTypes), len(stages))
+			}
+			for i, stage := range stages {
+				if stage.Type != expectedTypes[i] {
+					t.Errorf("wrong type of stage [%d]: expected=%s, found=%s", i, expectedTypes[i], stage.Type)
+				}
+				if i+1 < len(stages) {
+					if chain, ok := stage.Hook.(Chain); !ok || chain.Next() != stages[i+1].Hook {
+						t.Errorf("stage [%d] is not chained to the next stage", i)
+					}
+				}
+			}
+			if stages[0].Name != "limits" || stages[1].Name != "keyed_rate_limit" {
+				t.Errorf("wrong names of the stages: %s, %s", stages[0].Name, stages[1].Name)
+			}
+
+			if err := pipeline.Start(); err != nil {
+				t.Fatalf("failed to start the pipeline: %s", err)
+			}
+
+			sentMessages := make([]*logrus.Entry, 0)
+			for i := 0; i < 5; i++ {
+				entry := logrus.NewEntry(logrus.StandardLogger()).WithField("tenant", "acme")
+				entry.Message = fmt.Sprintf("test message: %d", i)
+				if err := pipeline.Fire(entry); err != nil {
+					t.Fatalf("fire failed at round [%d]: %s", i, err)
+				}
+				sentMessages = append(sentMessages, entry)
+			}
+
+			if err := pipeline.Stop(); err != nil {
+				t.Fatalf("failed to stop the pipeline: %s", err)
+			}
+
+			mockHook.compare(t, sentMessages)
+		})
+	}
+}
+
+func TestLoad_LevelFilter(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("recorder", &mockCannedHook{levels: logrus.AllLevels})
+
+	testData := []struct {
+		config   string
+		expected []logrus.Level
+	}{
+		{`
+sink: recorder
+stages:
+  - type: level_filter
+    levels: [debug, trace]
+`, []logrus.Level{logrus.DebugLevel, logrus.TraceLevel}},
+		{`
+sink: recorder
+stages:
+  - type: level_filter
+    min_level: error
+`, []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}},
+	}
+
+	for i, td := range testData {
+		pipeline, err := Load([]byte(td.config), registry)
+		if err != nil {
+			t.Fatalf("failed to load the pipeline at [test=%d]: %s", i, err)
+		}
+
+		levels := pipeline.Levels()
+		if fmt.Sprint(levels) != fmt.Sprint(td.expected) {
+			t.Errorf("wrong levels at [test=%d]: expected=%v, found=%v", i, td.expected, levels)
+		}
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	testData := []struct {
+		config   string
+		expected []string
+	}{
+		{`sink: missing`, []string{`sink: unknown sink "missing"`}},
+		{`stages: []`, []string{`sink: missing sink`}},
+		{`{sink: recorder, color: blue}`, []string{`color: unknown key`}},
+		{`
+sink: recorder
+stages:
+  - type: retry
+    delay: 10ms
+    retries: -1
+`, []string{`stages[0].retries: must not be negative, found -1`}},
+		{`
+sink: recorder
+stages:
+  - type: async
+    senders: 0
+    buffer_len: 0
+  - type: rate_limit
+    per_second: 0
+  - type: keyed_rate_limit
+    key: message
+    global: {per_second: 0}
+`, []string{
+			`stages[0].senders: must be at least 1, found 0`,
+			`stages[0].buffer_len: must be at least 1, found 0`,
+			`stages[1].per_second: must be at least 1, found 0`,
+			`stages[2].global.per_second: must be at least 1, found 0`,
+		}},
+		{`
+sink: recorder
+stages:
+  - type: rate_limit
+    per_second: 100
+    burst: 0
+    wait: 0s
+    level_limits:
+      - {per_second: 10, burst: 0, levels: [debug]}
+  - type: keyed_rate_limit
+    key: message
+    global: {per_second: 100, burst: 0}
+  - type: keyed_rate_limit
+    key: message
+    global: {burst: 10}
+`, []string{
+			`stages[0].burst: must be at least 1, found 0`,
+			`stages[0].wait: must be at least 1ms, found 0s`,
+			`stages[0].level_limits[0].burst: must be at least 1, found 0`,
+			`stages[1].global.burst: must be at least 1, found 0`,
+			`stages[2].global.per_second: missing rate`,
+		}},
+		{`
+sink: recorder
+stages:
+  - type: async
+  - type: retry
+  - type: rate_limit
+    bursts: 10
+    exempt: [error, severe]
+  - type: teleport
+  - type: sampling
+    rate: 2
+    level_rates: {warn: 0.5, loud: 1}
+  - type: dedup
+    window: soon
+  - type: level_filter
+    levels: [warn]
+    min_level: info
+  - type: level_filter
+    min_level: loud
+`, []string{
+			`stages[1].delay: missing duration`,
+			`stages[2].bursts: unknown key`,
+			`stages[2].exempt[1]: unknown log level "severe"`,
+			`stages[3].type: unknown stage type "teleport"`,
+			`stages[4].rate: must be between 0 and 1, found 2`,
+			`stages[4].level_rates.loud: unknown log level`,
+			`stages[5].window: invalid duration "soon"`,
+			`stages[6].min_level: levels and min_level are mutually exclusive`,
+			`stages[7].min_level: unknown log level "loud"`,
+		}},
+	}
+
+	registry := NewRegistry()
+	registry.Register("recorder", &mockCannedHook{})
+
+	for i, td := range testData {
+		_, err := Load([]byte(td.config), registry)
+		if err == nil {
+			t.Errorf("invalid configuration was loaded at [test=%d]", i)
+			continue
+		}
+
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) {
+			t.Errorf("error is not a ConfigError at [test=%d]: %s", i, err)
+		}
+
+		problems := strings.Split(err.Error(), "\n")
+		if len(problems) != len(td.expected) {
+			t.Errorf("wrong number of problems at [test=%d]: expected=%d, found=%d: %s",
+				i, len(td.expected), len(problems), err)
+		}
+		for _, expected := range td.expected {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("problem was not reported at [test=%d]: %s", i, expected)
+			}
+		}
+	}
+}
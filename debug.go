@@ -0,0 +1,147 @@
+package hooks
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// debugHandler serves the state of the pipelines of a registry
+type debugHandler struct {
+	registry *Registry
+	conf     debugParams
+}
+
+// debugParams defines the options of the debug handler
+type debugParams struct {
+	actions bool
+}
+
+// constructor --------------------------------------------------------
+
+// DebugOption is a functional option to update the debug handler configuration
+type DebugOption func(conf *debugParams)
+
+// AllowActions enables the POST requests that flush, pause and resume pipelines
+func AllowActions() DebugOption {
+	return func(conf *debugParams) {
+		conf.actions = true
+	}
+}
+
+// DebugHandler creates an HTTP handler that shows the pipelines of the registry
+//
+// GET requests return the state of all pipelines as JSON, or the state of
+// one pipeline when the "pipeline" query parameter names it. When actions
+// are allowed, POST requests with the "pipeline" and "action" parameters
+// flush, pause or resume the pipeline.
+func DebugHandler(registry *Registry, opts ...DebugOption) http.Handler {
+
+	handler := &debugHandler{
+		registry: registry,
+	}
+
+	for _, opt := range opts {
+		opt(&handler.conf)
+	}
+
+	return handler
+}
+
+// PublishExpvar publishes the state of the pipelines of the registry as an
+// expvar variable, the name must not be used by another variable
+func PublishExpvar(name string, registry *Registry) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return registryState(registry)
+	}))
+}
+
+// implementation -----------------------------------------------------
+
+// ServeHTTP serves the state of the pipelines and the actions on them
+func (h *debugHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.serveState(w, r)
+	case http.MethodPost:
+		if !h.conf.actions {
+			http.Error(w, "actions are not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.serveAction(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveState writes the state of one or all pipelines
+func (h *debugHandler) serveState(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("pipeline")
+	if name == "" {
+		writeJSON(w, registryState(h.registry))
+		return
+	}
+
+	pipeline, found := h.registry.Pipeline(name)
+	if !found {
+		http.Error(w, fmt.Sprintf("unknown pipeline %q", name), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, pipeline.State())
+}
+
+// serveAction runs an action on a pipeline and writes its new state
+func (h *debugHandler) serveAction(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("pipeline")
+	pipeline, found := h.registry.Pipeline(name)
+	if !found {
+		http.Error(w, fmt.Sprintf("unknown pipeline %q", name), http.StatusNotFound)
+		return
+	}
+
+	switch action := r.FormValue("action"); action {
+	case "flush":
+		if err := pipeline.Flush(); err != nil {
+			http.Error(w, fmt.Sprintf("failed to flush pipeline %q: %s", name, err),
+				http.StatusInternalServerError)
+			return
+		}
+	case "pause":
+		pipeline.Pause()
+	case "resume":
+		pipeline.Resume()
+	default:
+		http.Error(w, fmt.Sprintf("unknown action %q, expected flush, pause or resume", action),
+			http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, pipeline.State())
+}
+
+// registryState describes the state of all pipelines of a registry
+func registryState(registry *Registry) map[string]interface{} {
+	pipelines := registry.Pipelines()
+
+	states := make([]PipelineState, 0, len(pipelines))
+	for _, p := range pipelines {
+		states = append(states, p.State())
+	}
+
+	return map[string]interface{}{
+		"pipelines": states,
+	}
+}
+
+// writeJSON writes a value as the JSON body of the response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
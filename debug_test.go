@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newDebugRegistry creates a registry with one loaded pipeline
+func newDebugRegistry(t *testing.T, sink logrus.Hook) (*Registry, *Pipeline) {
+	registry := NewRegistry()
+	registry.Register("sink", sink)
+
+	pipeline, err := Load([]byte(`
+name: audit
+sink: sink
+stages:
+  - type: dedup
+    window: 1h
+  - type: rate_limit
+    per_second: 100
+    burst: 10
+    exempt: [error]
+  - type: async
+    senders: 1
+    buffer_len: 8
+`), registry)
+	if err != nil {
+		t.Fatalf("failed to load the pipeline: %s", err)
+	}
+	if err := pipeline.Start(); err != nil {
+		t.Fatalf("failed to start the pipeline: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = pipeline.Stop()
+	})
+
+	return registry, pipeline
+}
+
+func getState(t *testing.T, handler http.Handler, query string) (int, map[string]interface{}) {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/"+query, nil))
+
+	var state map[string]interface{}
+	if recorder.Code == http.StatusOK {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &state); err != nil {
+			t.Fatalf("invalid JSON in the response: %s", err)
+		}
+	}
+
+	return recorder.Code, state
+}
+
+func postAction(handler http.Handler, pipeline, action string) *httptest.ResponseRecorder {
+	form := url.Values{"pipeline": {pipeline}, "action": {action}}
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder
+}
+
+func TestDebugHandler_State(t *testing.T) {
+	registry, _ := newDebugRegistry(t, &mockCannedHook{})
+	handler := DebugHandler(registry)
+
+	code, state := getState(t, handler, "")
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status: %d", code)
+	}
+
+	pipelines, ok := state["pipelines"].([]interface{})
+	if !ok || len(pipelines) != 1 {
+		t.Fatalf("unexpected list of pipelines: %v", state["pipelines"])
+	}
+
+	pipeline := pipelines[0].(map[string]interface{})
+	if pipeline["name"] != "audit" || pipeline["running"] != true {
+		t.Errorf("unexpected state of the pipeline: %v", pipeline)
+	}
+
+	stages := pipeline["stages"].([]interface{})
+	expected := []string{"dedup", "rate_limit", "async", "sink"}
+	if len(stages) != len(expected) {
+		t.Fatalf("wrong number of stages: expected=%d, found=%d", len(expected), len(stages))
+	}
+	for i, stage := range stages {
+		if typ := stage.(map[string]interface{})["type"]; typ != expected[i] {
+			t.Errorf("wrong type of stage [%d]: expected=%s, found=%v", i, expected[i], typ)
+		}
+	}
+
+	async := stages[2].(map[string]interface{})["state"].(map[string]interface{})
+	if async["buffer_len"] != float64(8) || async["running"] != true {
+		t.Errorf("unexpected state of the async stage: %v", async)
+	}
+	limits := stages[1].(map[string]interface{})["state"].(map[string]interface{})
+	if exempt := limits["exempt"].([]interface{}); len(exempt) != 1 || exempt[0] != "error" {
+		t.Errorf("unexpected exempt levels of the rate limit stage: %v", limits["exempt"])
+	}
+
+	if code, _ := getState(t, handler, "?pipeline=audit"); code != http.StatusOK {
+		t.Errorf("unexpected status of a single pipeline: %d", code)
+	}
+	if code, _ := getState(t, handler, "?pipeline=missing"); code != http.StatusNotFound {
+		t.Errorf("unexpected status of a missing pipeline: %d", code)
+	}
+}
+
+func TestDebugHandler_Actions(t *testing.T) {
+	var mockHook mockRecordingHook
+	registry, pipeline := newDebugRegistry(t, &mockHook)
+
+	// actions are not allowed by default
+	if code := postAction(DebugHandler(registry), "audit", "pause").Code; code != http.StatusMethodNotAllowed {
+		t.Errorf("unexpected status of a forbidden action: %d", code)
+	}
+
+	handler := DebugHandler(registry, AllowActions())
+
+	if code := postAction(handler, "audit", "pause").Code; code != http.StatusOK {
+		t.Fatalf("unexpected status of the pause action: %d", code)
+	}
+	if err := pipeline.Fire(logrus.NewEntry(logrus.StandardLogger())); err != nil {
+		t.Errorf("paused pipeline returned an error: %s", err)
+	}
+
+	_, state := getState(t, handler, "?pipeline=audit")
+	if state["paused"] != true || state["dropped"] != 1.0 {
+		t.Errorf("pipeline is not reported as paused: %v", state)
+	}
+
+	if code := postAction(handler, "audit", "resume").Code; code != http.StatusOK {
+		t.Fatalf("unexpected status of the resume action: %d", code)
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "test message"
+	for i := 0; i < 3; i++ {
+		if err := pipeline.Fire(entry); err != nil {
+			t.Fatalf("resumed pipeline failed to fire at round [%d]: %s", i, err)
+		}
+	}
+
+	// flush reports the repeated messages and sends out the queued ones
+	if recorder := postAction(handler, "audit", "flush"); recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status of the flush action: %d: %s", recorder.Code, recorder.Body)
+	}
+	if n := mockHook.len(t); n != 2 {
+		t.Errorf("wrong number of delivered messages after the flush: expected=2, found=%d", n)
+	}
+
+	if code := postAction(handler, "audit", "explode").Code; code != http.StatusBadRequest {
+		t.Errorf("unexpected status of an unknown action: %d", code)
+	}
+	if code := postAction(handler, "missing", "flush").Code; code != http.StatusNotFound {
+		t.Errorf("unexpected status of a missing pipeline: %d", code)
+	}
+}
+
+func TestPublishExpvar(t *testing.T) {
+	registry, _ := newDebugRegistry(t, &mockCannedHook{})
+
+	// expvar keeps the variables for good, every run of the test needs a new name
+	name := "logrus_hooks_test"
+	for i := 1; expvar.Get(name) != nil; i++ {
+		name = fmt.Sprintf("logrus_hooks_test_%d", i)
+	}
+	PublishExpvar(name, registry)
+
+	variable := expvar.Get(name)
+	if variable == nil {
+		t.Fatalf("expvar variable was not published")
+	}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal([]byte(variable.String()), &state); err != nil {
+		t.Fatalf("invalid JSON in the expvar variable: %s", err)
+	}
+	if pipelines, ok := state["pipelines"].([]interface{}); !ok || len(pipelines) != 1 {
+		t.Errorf("unexpected list of pipelines: %v", state["pipelines"])
+	}
+}
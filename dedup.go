@@ -0,0 +1,369 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// default window and number of fingerprints of the dedup hook
+	defaultDedupWindow = 10 * time.Second
+	defaultDedupKeys   = 4096
+)
+
+// dedupHook is a Logrus hook that drops repeated messages
+type dedupHook struct {
+	sync.Mutex
+
+	ChainImpl
+	conf dedupParams
+
+	// seen holds the fingerprints of the messages in the current windows,
+	// ordered by the start of their windows
+	seen *keyCache[*repeated]
+
+	// the timer goroutine closes the windows while the hook is running, wake
+	// makes it look again at the windows after a change of the configuration
+	running   bool
+	stop      chan struct{}
+	wake      chan struct{}
+	done      sync.WaitGroup
+	errLogger *log.Logger
+}
+
+// dedupParams defines the fingerprints and the windows of the hook
+type dedupParams struct {
+	window  time.Duration
+	fields  []string
+	maxKeys int
+	clock   Clock
+}
+
+// repeated tracks the repeats of a message within its window
+type repeated struct {
+	entry *logrus.Entry
+	count int
+	start time.Time
+}
+
+// constructor --------------------------------------------------------
+
+// DedupOption is a functional option to update the dedup hook configuration
+type DedupOption func(conf *dedupParams)
+
+// DedupWindow sets the time during which repeats of a message are dropped
+func DedupWindow(d time.Duration) DedupOption {
+	return func(conf *dedupParams) {
+		if d > 0 {
+			conf.window = d
+		}
+	}
+}
+
+// DedupFields adds the values of fields to the fingerprint of the messages,
+// messages with different values of these fields are not repeats
+func DedupFields(names ...string) DedupOption {
+	return func(conf *dedupParams) {
+		conf.fields = append(conf.fields, names...)
+	}
+}
+
+// DedupMaxKeys sets the maximum number of fingerprints kept by the hook,
+// the oldest windows are closed early when the limit is reached
+func DedupMaxKeys(n int) DedupOption {
+	return func(conf *dedupParams) {
+		if n > 0 {
+			conf.maxKeys = n
+		}
+	}
+}
+
+// DedupClock sets the clock that drives the windows of the hook
+func DedupClock(c Clock) DedupOption {
+	return func(conf *dedupParams) {
+		conf.clock = c
+	}
+}
+
+// DedupHook creates a Logrus hook that drops repeated messages
+//
+// The first message with a given fingerprint, made of its level, message
+// and selected fields, is sent to the next hook and opens a window. The
+// repeats of the message during the window are dropped. When the window
+// closes a "repeated N times" message is sent to the next hook.
+//
+// The windows close when a message arrives after the window is over, and on
+// the timer of the clock while the hook is running.
+func DedupHook(next logrus.Hook, opts ...DedupOption) RunningHook {
+
+	hook := &dedupHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: dedupParams{
+			window:  defaultDedupWindow,
+			maxKeys: defaultDedupKeys,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	// fingerprints are evicted by the hook, the cache only keeps them in order
+	hook.seen = newKeyCache[*repeated](0, 0)
+	hook.wake = make(chan struct{}, 1)
+	hook.errLogger = log.New(os.Stderr, "", log.LstdFlags)
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends the first message of a window to the next hook and drops the repeats
+func (h *dedupHook) Fire(entry *logrus.Entry) error {
+	h.Lock()
+	now := clockOrSystem(h.conf.clock).Now()
+	key := h.fingerprint(entry)
+	closed := h.closeWindows(now)
+
+	rep, found := h.seen.peek(key)
+	if found {
+		rep.count++
+	} else {
+		h.seen.add(key, &repeated{entry: copyEntry(entry), start: now}, now)
+		closed = append(closed, h.evictOverflow()...)
+	}
+	h.Unlock()
+
+	var errs []error
+	for _, rep := range closed {
+		errs = append(errs, h.report(rep, now))
+	}
+	if !found {
+		errs = append(errs, h.next.Fire(entry))
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsRunning queries the state of the hook
+func (h *dedupHook) IsRunning() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.running
+}
+
+// Start launches the timer that closes the windows
+func (h *dedupHook) Start() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.running {
+		return nil
+	}
+
+	clock := clockOrSystem(h.conf.clock)
+	h.stop = make(chan struct{})
+	h.done.Add(1)
+	go h.ticker(clock.NewTimer(h.untilClose(clock.Now())), h.stop)
+
+	h.running = true
+
+	return nil
+}
+
+// Stop stops the timer and reports the repeated messages of all open windows
+func (h *dedupHook) Stop() error {
+	h.Lock()
+	if h.running {
+		close(h.stop)
+		h.running = false
+	}
+	h.Unlock()
+
+	h.done.Wait()
+
+	return h.Flush()
+}
+
+// Flush closes all open windows and reports the repeated messages
+func (h *dedupHook) Flush() error {
+	h.Lock()
+	now := clockOrSystem(h.conf.clock).Now()
+	closed := h.seen.removeAll()
+	h.Unlock()
+
+	var errs []error
+	for _, rep := range closed {
+		errs = append(errs, h.report(rep, now))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Reconfigure changes the windows and the fingerprints of the messages, the
+// windows that are closed early by a smaller limit on fingerprints are reported
+func (h *dedupHook) Reconfigure(opts ...DedupOption) error {
+	h.Lock()
+	now := clockOrSystem(h.conf.clock).Now()
+	conf := h.conf
+	conf.fields = append([]string(nil), h.conf.fields...)
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	h.conf = conf
+	closed := h.evictOverflow()
+	h.Unlock()
+
+	// the timer may be waiting for the end of a longer window
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+
+	var errs []error
+	for _, rep := range closed {
+		errs = append(errs, h.report(rep, now))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Inspect describes the configuration of the hook and the number of open windows
+func (h *dedupHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	return map[string]interface{}{
+		"window":       h.conf.window.String(),
+		"fields":       append([]string{}, h.conf.fields...),
+		"max_keys":     h.conf.maxKeys,
+		"fingerprints": h.seen.len(),
+		"running":      h.running,
+	}
+}
+
+// ticker closes the windows on the timer until the hook is stopped
+func (h *dedupHook) ticker(timer Timer, stop chan struct{}) {
+	defer h.done.Done()
+
+	for {
+		select {
+		case <-timer.C():
+			if err := h.closeExpired(); err != nil {
+				h.errLogger.Printf("dedup logrus hook: %s", err)
+			}
+		case <-h.wake:
+			timer.Stop()
+		case <-stop:
+			timer.Stop()
+			return
+		}
+
+		h.Lock()
+		d := h.untilClose(clockOrSystem(h.conf.clock).Now())
+		h.Unlock()
+		timer.Reset(d)
+	}
+}
+
+// closeExpired closes the windows that are over and reports the repeated messages
+func (h *dedupHook) closeExpired() error {
+	h.Lock()
+	now := clockOrSystem(h.conf.clock).Now()
+	closed := h.closeWindows(now)
+	h.Unlock()
+
+	var errs []error
+	for _, rep := range closed {
+		errs = append(errs, h.report(rep, now))
+	}
+
+	return errors.Join(errs...)
+}
+
+// untilClose is the time until the oldest window closes, or the length of a
+// window when there are no windows
+// note: this function must be called with the hook's mutex locked
+func (h *dedupHook) untilClose(now time.Time) time.Duration {
+	_, rep, found := h.seen.oldest()
+	if !found {
+		return h.conf.window
+	}
+
+	return max(0, rep.start.Add(h.conf.window).Sub(now))
+}
+
+// fingerprint identifies the repeats of a message
+func (h *dedupHook) fingerprint(entry *logrus.Entry) string {
+	if entry == nil {
+		return ""
+	}
+
+	var key strings.Builder
+	fmt.Fprintf(&key, "%d|%s", entry.Level, entry.Message)
+	for _, name := range h.conf.fields {
+		if value, found := entry.Data[name]; found {
+			fmt.Fprintf(&key, "|%s=%v", name, value)
+		}
+	}
+
+	return key.String()
+}
+
+// closeWindows removes the fingerprints of the windows that are over
+// note: this function must be called with the hook's mutex locked
+func (h *dedupHook) closeWindows(now time.Time) []*repeated {
+	var closed []*repeated
+	for {
+		_, rep, found := h.seen.oldest()
+		if !found || now.Sub(rep.start) < h.conf.window {
+			return closed
+		}
+		h.seen.removeOldest()
+		closed = append(closed, rep)
+	}
+}
+
+// evictOverflow removes the oldest fingerprints over the limit
+// note: this function must be called with the hook's mutex locked
+func (h *dedupHook) evictOverflow() []*repeated {
+	var closed []*repeated
+	for h.seen.len() > h.conf.maxKeys {
+		_, rep, _ := h.seen.oldest()
+		h.seen.removeOldest()
+		closed = append(closed, rep)
+	}
+
+	return closed
+}
+
+// report sends the number of repeats of a message to the next hook
+func (h *dedupHook) report(rep *repeated, now time.Time) error {
+	if rep.count == 0 {
+		return nil
+	}
+
+	summary := syntheticEntry(rep.entry, rep.entry.Level,
+		fmt.Sprintf("%s (repeated %d times in the last %s)",
+			rep.entry.Message, rep.count, roundDuration(now.Sub(rep.start))),
+		now)
+	for k, v := range rep.entry.Data {
+		summary.Data[k] = v
+	}
+	summary.Data["repeated"] = rep.count
+
+	return h.next.Fire(summary)
+}
@@ -0,0 +1,129 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// findRepeated looks up the report of the repeats of a message
+func findRepeated(mock *mockRecordingHook, message string) *logrus.Entry {
+	var found *logrus.Entry
+	mock.messages.Range(func(_, value interface{}) bool {
+		msg := value.(*logrus.Entry)
+		if msg.Data["repeated"] != nil && msg.Data["original"] == message {
+			found = msg
+		}
+		return true
+	})
+
+	return found
+}
+
+func newDedupEntry(message string, fields logrus.Fields) *logrus.Entry {
+	entry := newTestEntry(logrus.WarnLevel, message, fields)
+	entry.Data["original"] = message
+
+	return entry
+}
+
+func TestDedup_Fields(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := DedupHook(&mockHook, DedupFields("user"))
+
+	for _, user := range []string{"alice", "bob", "alice"} {
+		entry := newDedupEntry("test message from "+user, logrus.Fields{"user": user})
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	if n := mockHook.len(t); n != 2 {
+		t.Errorf("wrong number of delivered messages: expected=2, found=%d", n)
+	}
+}
+
+func TestDedup_MaxKeys(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := DedupHook(&mockHook, DedupMaxKeys(2))
+
+	for _, msg := range []string{"a", "a", "a", "b", "c"} {
+		if err := hook.Fire(newDedupEntry(msg, nil)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	// the window of "a" was closed early to make room for "c"
+	report := findRepeated(&mockHook, "a")
+	if report == nil {
+		t.Fatalf("repeats of the evicted message were not reported")
+	}
+	if n := report.Data["repeated"]; n != 2 {
+		t.Errorf("wrong number of repeats: expected=2, found=%v", n)
+	}
+
+	theHook, ok := hook.(*dedupHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected dedupHook type: %v", hook)
+	}
+	if n := theHook.seen.len(); n != 2 {
+		t.Errorf("wrong number of fingerprints: expected=2, found=%d", n)
+	}
+}
+
+func TestDedup_Flush(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := DedupHook(&mockHook)
+
+	for i := 0; i < 5; i++ {
+		if err := hook.Fire(newDedupEntry("test message", nil)); err != nil {
+			t.Fatalf("fire failed at round [%d]: %s", i, err)
+		}
+	}
+
+	theHook, ok := hook.(*dedupHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected dedupHook type: %v", hook)
+	}
+	if err := theHook.Flush(); err != nil {
+		t.Fatalf("flush failed: %s", err)
+	}
+
+	report := findRepeated(&mockHook, "test message")
+	if report == nil {
+		t.Fatalf("repeats of the message were not reported")
+	}
+	if n := report.Data["repeated"]; n != 4 {
+		t.Errorf("wrong number of repeats: expected=4, found=%v", n)
+	}
+	if n := theHook.seen.len(); n != 0 {
+		t.Errorf("fingerprints were not removed: %d", n)
+	}
+}
+
+func TestDedup_Reconfigure(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := DedupHook(&mockHook)
+
+	for _, msg := range []string{"a", "a", "b", "b", "b"} {
+		if err := hook.Fire(newDedupEntry(msg, nil)); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	theHook, ok := hook.(Reconfigurable[DedupOption])
+	if !ok {
+		t.Fatalf("dedup hook can not be reconfigured: %v", hook)
+	}
+	if err := theHook.Reconfigure(DedupMaxKeys(1)); err != nil {
+		t.Fatalf("failed to reconfigure the dedup hook: %s", err)
+	}
+
+	// the window of "a" was closed to make room under the new limit
+	if report := findRepeated(&mockHook, "a"); report == nil {
+		t.Errorf("repeats of the evicted message were not reported")
+	}
+	if report := findRepeated(&mockHook, "b"); report != nil {
+		t.Errorf("repeats of the message in an open window were reported")
+	}
+}
@@ -0,0 +1,238 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConflictPolicy decides what happens when an added field is already in the message
+type ConflictPolicy int
+
+const (
+	// ConflictKeep keeps the value of the message, this is the default policy
+	ConflictKeep ConflictPolicy = iota
+
+	// ConflictOverwrite replaces the value of the message with the added value
+	ConflictOverwrite
+
+	// ConflictRename moves the value of the message to "fields.<key>", the
+	// way Logrus does for fields that clash with its own keys
+	ConflictRename
+)
+
+// kubernetesEnv maps the environment variables that are commonly set from the
+// downward API to the names of the fields
+var kubernetesEnv = map[string]string{
+	"POD_NAME":      "k8s.pod",
+	"POD_NAMESPACE": "k8s.namespace",
+	"POD_IP":        "k8s.pod_ip",
+	"NODE_NAME":     "k8s.node",
+}
+
+// enrichHook is a Logrus hook that adds fields to the messages
+type enrichHook struct {
+	ChainImpl
+	conf enrichParams
+}
+
+// enrichParams defines the fields added by the hook
+type enrichParams struct {
+	fields   []enrichField
+	conflict ConflictPolicy
+}
+
+// enrichField is a field with a static value or a value computed for every message
+type enrichField struct {
+	key   string
+	value interface{}
+	fn    func(entry *logrus.Entry) interface{}
+}
+
+// constructor --------------------------------------------------------
+
+// EnrichOption is a functional option to update the enrich hook configuration
+type EnrichOption func(conf *enrichParams)
+
+// StaticFields adds fields with fixed values
+func StaticFields(fields logrus.Fields) EnrichOption {
+	return func(conf *enrichParams) {
+		for _, key := range sortedKeys(fields) {
+			conf.add(enrichField{key: key, value: fields[key]})
+		}
+	}
+}
+
+// LazyField adds a field whose value is computed for every message, the
+// function is not called when the value would not be used
+func LazyField(key string, fn func(entry *logrus.Entry) interface{}) EnrichOption {
+	return func(conf *enrichParams) {
+		conf.add(enrichField{key: key, fn: fn})
+	}
+}
+
+// HostFields adds the name of the host and the process id
+func HostFields() EnrichOption {
+	return func(conf *enrichParams) {
+		if hostname, err := os.Hostname(); err == nil {
+			conf.add(enrichField{key: "hostname", value: hostname})
+		}
+		conf.add(enrichField{key: "pid", value: os.Getpid()})
+	}
+}
+
+// ServiceFields adds the name, the version and the git commit of the service,
+// empty values are skipped
+func ServiceFields(name, version, commit string) EnrichOption {
+	return func(conf *enrichParams) {
+		for _, f := range []enrichField{
+			{key: "service", value: name},
+			{key: "version", value: version},
+			{key: "git_sha", value: commit},
+		} {
+			if f.value != "" {
+				conf.add(f)
+			}
+		}
+	}
+}
+
+// KubernetesFields adds the pod metadata from the environment variables
+// POD_NAME, POD_NAMESPACE, POD_IP and NODE_NAME, and from the files of the
+// downward API volume mounted at dir, if dir is not empty
+//
+// Every file of the volume becomes a field named "k8s.<file name>", like
+// k8s.labels for the file with the labels of the pod.
+func KubernetesFields(dir string) EnrichOption {
+	return func(conf *enrichParams) {
+		for _, name := range sortedKeys(kubernetesEnv) {
+			if value := os.Getenv(name); value != "" {
+				conf.add(enrichField{key: kubernetesEnv[name], value: value})
+			}
+		}
+
+		if dir == "" {
+			return
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, file := range files {
+			// the files of the volume are symlinks to hidden directories
+			if strings.HasPrefix(file.Name(), ".") || file.IsDir() {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(dir, file.Name()))
+			if err != nil {
+				continue
+			}
+			conf.add(enrichField{
+				key:   "k8s." + file.Name(),
+				value: strings.TrimSpace(string(content)),
+			})
+		}
+	}
+}
+
+// OnConflict sets the policy for fields that are already in the message
+func OnConflict(policy ConflictPolicy) EnrichOption {
+	return func(conf *enrichParams) {
+		conf.conflict = policy
+	}
+}
+
+// EnrichHook creates a Logrus hook that adds fields to a copy of the message
+// before it is sent to the next hook
+//
+// The fields are added in the order of the options, a later option replaces
+// the field of an earlier one with the same key.
+func EnrichHook(next logrus.Hook, opts ...EnrichOption) logrus.Hook {
+
+	hook := &enrichHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends a copy of the message with the added fields to the next hook
+func (h *enrichHook) Fire(entry *logrus.Entry) error {
+	if entry == nil {
+		return h.next.Fire(entry)
+	}
+
+	enriched := copyEntry(entry)
+	for _, f := range h.conf.fields {
+		existing, found := enriched.Data[f.key]
+		if found && h.conf.conflict == ConflictKeep {
+			continue
+		}
+
+		value := f.value
+		if f.fn != nil {
+			value = f.fn(entry)
+		}
+		if found && h.conf.conflict == ConflictRename {
+			enriched.Data["fields."+f.key] = existing
+		}
+		enriched.Data[f.key] = value
+	}
+
+	return h.next.Fire(enriched)
+}
+
+// Inspect describes the fields added by the hook
+func (h *enrichHook) Inspect() map[string]interface{} {
+	fields := make(map[string]interface{}, len(h.conf.fields))
+	for _, f := range h.conf.fields {
+		if f.fn != nil {
+			fields[f.key] = "<lazy>"
+			continue
+		}
+		fields[f.key] = f.value
+	}
+
+	return map[string]interface{}{
+		"fields":   fields,
+		"conflict": h.conf.conflict.String(),
+	}
+}
+
+// String is the name of the policy
+func (p ConflictPolicy) String() string {
+	switch p {
+	case ConflictKeep:
+		return "keep"
+	case ConflictOverwrite:
+		return "overwrite"
+	case ConflictRename:
+		return "rename"
+	}
+
+	return "unknown"
+}
+
+// add adds a field, replacing the field with the same key
+func (conf *enrichParams) add(field enrichField) {
+	for i := range conf.fields {
+		if conf.fields[i].key == field.key {
+			conf.fields[i] = field
+			return
+		}
+	}
+
+	conf.fields = append(conf.fields, field)
+}
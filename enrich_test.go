@@ -0,0 +1,156 @@
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestEnrich_Fields(t *testing.T) {
+	var mockHook mockLastHook
+
+	calls := 0
+	hook := EnrichHook(&mockHook,
+		StaticFields(logrus.Fields{"region": "eu", "zone": "a"}),
+		ServiceFields("billing", "1.2.3", ""),
+		HostFields(),
+		LazyField("calls", func(*logrus.Entry) interface{} {
+			calls++
+			return calls
+		}),
+	)
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField("user", "alice")
+	for i := 1; i <= 2; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+		if mockHook.entry.Data["calls"] != i {
+			t.Errorf("lazy field was not computed for message [%d]: %v", i, mockHook.entry.Data["calls"])
+		}
+	}
+
+	data := mockHook.entry.Data
+	expected := logrus.Fields{
+		"user":    "alice",
+		"region":  "eu",
+		"zone":    "a",
+		"service": "billing",
+		"version": "1.2.3",
+		"pid":     os.Getpid(),
+	}
+	for k, v := range expected {
+		if data[k] != v {
+			t.Errorf("wrong value of field %q: expected=%v, found=%v", k, v, data[k])
+		}
+	}
+	if _, found := data["git_sha"]; found {
+		t.Errorf("empty service field was added")
+	}
+	if _, found := data["hostname"]; !found {
+		t.Errorf("hostname was not added")
+	}
+
+	if len(entry.Data) != 1 {
+		t.Errorf("original entry was changed: %v", entry.Data)
+	}
+}
+
+func TestEnrich_Conflict(t *testing.T) {
+	testData := []struct {
+		policy   ConflictPolicy
+		expected logrus.Fields
+		calls    int
+	}{
+		{ConflictKeep, logrus.Fields{"service": "caller", "computed": "caller"}, 0},
+		{ConflictOverwrite, logrus.Fields{"service": "billing", "computed": "lazy"}, 1},
+		{ConflictRename, logrus.Fields{
+			"service":         "billing",
+			"fields.service":  "caller",
+			"computed":        "lazy",
+			"fields.computed": "caller",
+		}, 1},
+	}
+
+	for _, td := range testData {
+		var mockHook mockLastHook
+
+		calls := 0
+		hook := EnrichHook(&mockHook,
+			ServiceFields("billing", "", ""),
+			LazyField("computed", func(*logrus.Entry) interface{} {
+				calls++
+				return "lazy"
+			}),
+			OnConflict(td.policy),
+		)
+
+		entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+			"service":  "caller",
+			"computed": "caller",
+		})
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+
+		data := mockHook.entry.Data
+		if len(data) != len(td.expected) {
+			t.Errorf("policy %s: wrong fields: expected=%v, found=%v", td.policy, td.expected, data)
+		}
+		for k, v := range td.expected {
+			if data[k] != v {
+				t.Errorf("policy %s: wrong value of field %q: expected=%v, found=%v", td.policy, k, v, data[k])
+			}
+		}
+		if calls != td.calls {
+			t.Errorf("policy %s: wrong number of calls of the lazy field: expected=%d, found=%d", td.policy, td.calls, calls)
+		}
+	}
+}
+
+func TestEnrich_Kubernetes(t *testing.T) {
+	t.Setenv("POD_NAME", "billing-7d4f")
+	t.Setenv("POD_NAMESPACE", "payments")
+	t.Setenv("POD_IP", "")
+	t.Setenv("NODE_NAME", "")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "labels"), []byte("app=\"billing\"\n"), 0o644); err != nil {
+		t.Fatalf("failed to write the labels: %s", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "..data"), 0o755); err != nil {
+		t.Fatalf("failed to create the data directory: %s", err)
+	}
+
+	var mockHook mockLastHook
+	hook := EnrichHook(&mockHook, KubernetesFields(dir))
+
+	if err := hook.Fire(logrus.NewEntry(logrus.StandardLogger())); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	expected := logrus.Fields{
+		"k8s.pod":       "billing-7d4f",
+		"k8s.namespace": "payments",
+		"k8s.labels":    `app="billing"`,
+	}
+	data := mockHook.entry.Data
+	if len(data) != len(expected) {
+		t.Errorf("wrong fields: expected=%v, found=%v", expected, data)
+	}
+	for k, v := range expected {
+		if data[k] != v {
+			t.Errorf("wrong value of field %q: expected=%v, found=%v", k, v, data[k])
+		}
+	}
+}
+
+func TestEnrich_Nil(t *testing.T) {
+	hook := EnrichHook(&mockCannedHook{}, HostFields())
+
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("fire failed: %s", err)
+	}
+}
@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// copyEntry makes a copy of a log entry that can be changed without
+// affecting the original entry
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	if entry == nil {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	dup := *entry
+	dup.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		dup.Data[k] = v
+	}
+
+	return &dup
+}
+
+// sortedKeys lists the keys of a map, like the fields of a log entry, in
+// sorted order
+func sortedKeys[M ~map[string]V, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
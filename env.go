@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by Env
+const DefaultEnvPrefix = "LOGRUS_HOOKS"
+
+// Env reads the options of the hooks from environment variables
+//
+// The options from the environment are applied on top of the options given
+// in code, so the precedence from the lowest to the highest is:
+//
+//  1. the defaults of the hook
+//  2. the options passed to the hook constructor
+//  3. the environment variables
+//
+// The variables are named after the prefix and the option:
+//
+//	<PREFIX>_RETRY_DELAY          base delay between retries, like "100ms"
+//	<PREFIX>_RETRY_MAX            maximum number of retries
+//	<PREFIX>_RETRY_FACTOR_PCT     increase of the delay after each retry
+//	<PREFIX>_RETRY_JITTER_PCT     random delay added to each retry
+//	<PREFIX>_RATE_PER_SECOND      maximum number of messages per second
+//	<PREFIX>_RATE_BURST           maximum number of messages in a burst
+//	<PREFIX>_RATE_MAX_WAIT        wait for the rate limit up to this long, like "1s"
+//	<PREFIX>_ASYNC_SENDERS        number of sender goroutines
+//	<PREFIX>_ASYNC_BOOST_SENDERS  number of extra sender goroutines
+//	<PREFIX>_ASYNC_BUFFER         number of messages that can be queued
+//
+// Variables with bad values are ignored and reported by Err.
+type Env struct {
+	sync.Mutex
+
+	prefix string
+
+	// errs holds the problems with the variables, by name of the variable
+	errs map[string]error
+}
+
+// NewEnv creates a source of options with the given prefix of the variables,
+// an empty prefix selects the default one
+func NewEnv(prefix string) *Env {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+
+	return &Env{
+		prefix: strings.TrimSuffix(prefix, "_"),
+		errs:   make(map[string]error),
+	}
+}
+
+// RetryOptions adds the retry options from the environment to the given ones
+func (e *Env) RetryOptions(opts ...RetryOption) []RetryOption {
+	if d, found := e.duration("RETRY_DELAY", 0); found {
+		opts = append(opts, RetryDelay(d))
+	}
+	if n, found := e.int("RETRY_MAX", 0, math.MaxInt32); found {
+		opts = append(opts, Retries(int(n)))
+	}
+	if n, found := e.int("RETRY_FACTOR_PCT", 0, math.MaxInt32); found {
+		opts = append(opts, FactorPct(n))
+	}
+	if n, found := e.int("RETRY_JITTER_PCT", 0, math.MaxInt32); found {
+		opts = append(opts, JitterPct(n))
+	}
+
+	return opts
+}
+
+// RateLimitOptions adds the rate limit options from the environment to the given ones
+func (e *Env) RateLimitOptions(opts ...RateLimitOption) []RateLimitOption {
+	if n, found := e.int("RATE_PER_SECOND", 1, math.MaxInt32); found {
+		opts = append(opts, PerSecond(int(n)))
+	}
+	if n, found := e.int("RATE_BURST", 1, math.MaxInt32); found {
+		opts = append(opts, Burst(int(n)))
+	}
+	// a zero maximum wait would block the callers without a bound
+	if d, found := e.duration("RATE_MAX_WAIT", time.Millisecond); found {
+		opts = append(opts, Wait(d))
+	}
+
+	return opts
+}
+
+// AsyncOptions adds the async options from the environment to the given ones
+func (e *Env) AsyncOptions(opts ...AsyncOption) []AsyncOption {
+	if n, found := e.int("ASYNC_SENDERS", 1, math.MaxInt32); found {
+		opts = append(opts, Senders(uint32(n)))
+	}
+	if n, found := e.int("ASYNC_BOOST_SENDERS", 0, math.MaxInt32); found {
+		opts = append(opts, BoostSenders(uint32(n)))
+	}
+	if n, found := e.int("ASYNC_BUFFER", 1, math.MaxInt32); found {
+		opts = append(opts, BufferLen(uint32(n)))
+	}
+
+	return opts
+}
+
+// Err reports the variables whose values were ignored, each one as a
+// *ConfigError with the name of the variable as path
+func (e *Env) Err() error {
+	e.Lock()
+	defer e.Unlock()
+
+	names := make([]string, 0, len(e.errs))
+	for name := range e.errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, 0, len(names))
+	for _, name := range names {
+		errs = append(errs, e.errs[name])
+	}
+
+	return errors.Join(errs...)
+}
+
+// lookup reads a variable and clears any problem reported for it before
+func (e *Env) lookup(option string) (string, string, bool) {
+	name := e.prefix + "_" + option
+	value, found := os.LookupEnv(name)
+
+	e.Lock()
+	delete(e.errs, name)
+	e.Unlock()
+
+	return name, strings.TrimSpace(value), found && strings.TrimSpace(value) != ""
+}
+
+// fail records a problem with the value of a variable
+func (e *Env) fail(name, problem string) {
+	e.Lock()
+	defer e.Unlock()
+
+	e.errs[name] = &ConfigError{Path: name, Problem: problem}
+}
+
+// int reads an integer between min and max
+func (e *Env) int(option string, min, max int64) (int64, bool) {
+	name, value, found := e.lookup(option)
+	if !found {
+		return 0, false
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	switch {
+	case err != nil:
+		e.fail(name, fmt.Sprintf("expected an integer, found %q", value))
+		return 0, false
+	case n < min || n > max:
+		e.fail(name, fmt.Sprintf("must be between %d and %d, found %d", min, max, n))
+		return 0, false
+	}
+
+	return n, true
+}
+
+// duration reads a duration like "100ms" that is not less than min
+func (e *Env) duration(option string, min time.Duration) (time.Duration, bool) {
+	name, value, found := e.lookup(option)
+	if !found {
+		return 0, false
+	}
+
+	d, err := time.ParseDuration(value)
+	switch {
+	case err != nil:
+		e.fail(name, fmt.Sprintf(`expected a duration like "100ms", found %q`, value))
+		return 0, false
+	case d < min && min == 0:
+		e.fail(name, fmt.Sprintf("must not be negative, found %s", value))
+		return 0, false
+	case d < min:
+		e.fail(name, fmt.Sprintf("must be at least %s, found %s", min, value))
+		return 0, false
+	}
+
+	return d, true
+}
@@ -0,0 +1,125 @@
+package hooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnv_RetryOptions(t *testing.T) {
+	t.Setenv("TEST_RETRY_DELAY", "250ms")
+	t.Setenv("TEST_RETRY_MAX", "7")
+	t.Setenv("TEST_RETRY_FACTOR_PCT", "50")
+	t.Setenv("TEST_RETRY_JITTER_PCT", " 5 ")
+
+	env := NewEnv("TEST_")
+	hook := RetryHook(&mockCannedHook{}, time.Second, env.RetryOptions(Retries(1), JitterPct(20))...)
+
+	theHook, ok := hook.(*retryHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected retryHook type: %v", hook)
+	}
+
+	// the environment takes precedence over the options in code
+	expected := backoff{
+		retryDelay: 250 * time.Millisecond,
+		factorPct:  50,
+		jitterPct:  5,
+		maxRetries: 7,
+	}
+	if theHook.backoff != expected {
+		t.Errorf("wrong backoff: expected=%+v, found=%+v", expected, theHook.backoff)
+	}
+	if err := env.Err(); err != nil {
+		t.Errorf("unexpected problems with the environment: %s", err)
+	}
+}
+
+func TestEnv_RateLimitOptions(t *testing.T) {
+	t.Setenv("LOGRUS_HOOKS_RATE_PER_SECOND", "25")
+	t.Setenv("LOGRUS_HOOKS_RATE_BURST", "5")
+	t.Setenv("LOGRUS_HOOKS_RATE_MAX_WAIT", "1s")
+
+	env := NewEnv("")
+	hook := RateLimitHook(&mockCannedHook{}, env.RateLimitOptions(PerSecond(1))...)
+
+	theHook, ok := hook.(*rareLimitHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected rareLimitHook type: %v", hook)
+	}
+
+	conf := theHook.conf
+	if conf.limitPeSecond != 25 || conf.burst != 5 || !conf.wait || conf.maxWait != time.Second {
+		t.Errorf("wrong rate limit: %+v", conf)
+	}
+}
+
+func TestEnv_AsyncOptions(t *testing.T) {
+	t.Setenv("LOGRUS_HOOKS_ASYNC_SENDERS", "3")
+	t.Setenv("LOGRUS_HOOKS_ASYNC_BOOST_SENDERS", "6")
+	t.Setenv("LOGRUS_HOOKS_ASYNC_BUFFER", "9")
+
+	env := NewEnv("")
+	hook := AsyncHook(&mockCannedHook{}, env.AsyncOptions(BufferLen(1))...)
+
+	theHook, ok := hook.(*asyncHook)
+	if !ok {
+		t.Fatalf("test hook is not of the expected asyncHook type: %v", hook)
+	}
+
+	conf := theHook.conf
+	if conf.numSenders != 3 || conf.numBoostSenders != 6 || conf.bufferLen != 9 {
+		t.Errorf("wrong async configuration: %+v", conf)
+	}
+}
+
+func TestEnv_Errors(t *testing.T) {
+	t.Setenv("LOGRUS_HOOKS_RETRY_MAX", "-1")
+	t.Setenv("LOGRUS_HOOKS_RETRY_DELAY", "soon")
+	t.Setenv("LOGRUS_HOOKS_ASYNC_BUFFER", "many")
+	t.Setenv("LOGRUS_HOOKS_RATE_BURST", "0")
+	t.Setenv("LOGRUS_HOOKS_RATE_MAX_WAIT", "0s")
+	t.Setenv("LOGRUS_HOOKS_ASYNC_SENDERS", "0")
+
+	env := NewEnv("")
+	retryOpts := env.RetryOptions(Retries(2))
+	asyncOpts := env.AsyncOptions()
+	rateOpts := env.RateLimitOptions()
+
+	// bad values are ignored
+	if len(retryOpts) != 1 || len(asyncOpts) != 0 || len(rateOpts) != 0 {
+		t.Errorf("wrong number of options: retry=%d, async=%d, rate limit=%d",
+			len(retryOpts), len(asyncOpts), len(rateOpts))
+	}
+
+	err := env.Err()
+	if err == nil {
+		t.Fatalf("bad values were not reported")
+	}
+
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("error is not a ConfigError: %s", err)
+	}
+
+	expected := []string{
+		`LOGRUS_HOOKS_ASYNC_BUFFER: expected an integer, found "many"`,
+		`LOGRUS_HOOKS_ASYNC_SENDERS: must be between 1 and 2147483647, found 0`,
+		`LOGRUS_HOOKS_RATE_BURST: must be between 1 and 2147483647, found 0`,
+		`LOGRUS_HOOKS_RATE_MAX_WAIT: must be at least 1ms, found 0s`,
+		`LOGRUS_HOOKS_RETRY_DELAY: expected a duration like "100ms", found "soon"`,
+		`LOGRUS_HOOKS_RETRY_MAX: must be between 0 and 2147483647, found -1`,
+	}
+	if problems := err.Error(); problems != strings.Join(expected, "\n") {
+		t.Errorf("wrong problems were reported:\n%s", problems)
+	}
+
+	// fixed values are not reported anymore
+	t.Setenv("LOGRUS_HOOKS_RETRY_MAX", "1")
+	t.Setenv("LOGRUS_HOOKS_RETRY_DELAY", "1s")
+	env.RetryOptions()
+	if problems := env.Err().Error(); problems != strings.Join(expected[:4], "\n") {
+		t.Errorf("wrong problems were reported after the fix:\n%s", problems)
+	}
+}
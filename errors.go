@@ -0,0 +1,63 @@
+package hooks
+
+import (
+	"fmt"
+	"time"
+)
+
+// RateLimitError is returned by the rate limit hooks when a message is
+// dropped because it does not fit in the rate limit
+type RateLimitError struct {
+	// Limit and Burst describe the limit that was exceeded
+	Limit float64
+	Burst int
+
+	// Err is the reason the wait for the limit failed, in wait mode
+	Err error
+
+	// Delay is how long until the limit would permit the message, it is
+	// reported without blocking when the hook does not wait and is zero
+	// when it is not known
+	Delay time.Duration
+}
+
+// Error describes the rate limit that was exceeded
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("rate limit [%f/sec, burst=%d] exceeded", e.Limit, e.Burst)
+	if e.Delay > 0 {
+		msg += fmt.Sprintf(", retry in %s", e.Delay)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns the reason the wait for the limit failed
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RetryExhaustedError is returned by the retry hook when all attempts to
+// deliver a message failed
+type RetryExhaustedError struct {
+	// Attempts is the number of times the message was sent to the next hook
+	Attempts int
+
+	// Elapsed is the time spent on all attempts and the pauses between them
+	Elapsed time.Duration
+
+	// Last is the error of the last attempt
+	Last error
+}
+
+// Error describes the failed attempts
+func (e *RetryExhaustedError) Error() string {
+	return fmt.Sprintf("failed after [%d] attempts in %s: %s", e.Attempts, e.Elapsed, e.Last)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *RetryExhaustedError) Unwrap() error {
+	return e.Last
+}
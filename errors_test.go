@@ -0,0 +1,94 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestErrors_RateLimit(t *testing.T) {
+	hook := RateLimitHook(&mockCannedHook{}, PerSecond(2), Burst(1))
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message was dropped: %s", err)
+	}
+
+	err := hook.Fire(nil)
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("unexpected error of a dropped message: %v", err)
+	}
+	if limitErr.Limit != 2 || limitErr.Burst != 1 {
+		t.Errorf("wrong rate limit in the error: %+v", limitErr)
+	}
+}
+
+func TestErrors_RateLimitWait(t *testing.T) {
+	hook := RateLimitHook(&mockCannedHook{}, PerSecond(1), Burst(1), Wait(0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("first message was dropped: %s", err)
+	}
+
+	err := hook.Fire(entry)
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("unexpected error of a dropped message: %v", err)
+	}
+	if limitErr.Err == nil {
+		t.Errorf("error of a dropped message has no reason: %+v", limitErr)
+	}
+}
+
+func TestErrors_RetryExhausted(t *testing.T) {
+	hook := RetryHook(
+		RateLimitHook(&mockCannedHook{}, PerSecond(1), Burst(1)),
+		time.Microsecond,
+		Retries(2),
+	)
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message failed: %s", err)
+	}
+
+	err := hook.Fire(nil)
+	var retryErr *RetryExhaustedError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("unexpected error after all retries: %v", err)
+	}
+	if retryErr.Attempts != 3 {
+		t.Errorf("wrong number of attempts: expected=3, found=%d", retryErr.Attempts)
+	}
+	if retryErr.Elapsed <= 0 {
+		t.Errorf("time of the attempts is missing: %s", retryErr.Elapsed)
+	}
+
+	// the drop by the rate limit is found through the retry error
+	var limitErr *RateLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf("rate limit error is not found in: %v", err)
+	}
+}
+
+func TestErrors_Chain(t *testing.T) {
+	async := AsyncHook(&mockCannedHook{})
+	pipeline := NewPipeline("test",
+		Stage{Name: "retry", Type: "retry", Hook: RetryHook(async, 0, Retries(1))},
+		Stage{Name: "async", Type: "async", Hook: async},
+	)
+
+	err := pipeline.Fire(nil)
+	if !errors.Is(err, ErrNotRunning) {
+		t.Errorf("async hook error is not found in: %v", err)
+	}
+	if errors.Is(err, ErrBufferFull) {
+		t.Errorf("unexpected error is found in: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package hooks
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// levelFilterHook is a Logrus hook that decides which log levels reach the next hook
+type levelFilterHook struct {
+	ChainElement
+
+	levels  []logrus.Level
+	allowed map[logrus.Level]bool
+}
+
+// constructor --------------------------------------------------------
+
+// LevelFilterHook creates a Logrus hook that fires only for the given log levels
+//
+// The levels replace the levels of the next hook, they can be fewer or more
+// than what the next hook reports. Messages of other levels that are passed
+// to the hook by other hooks are dropped without an error.
+func LevelFilterHook(next logrus.Hook, levels ...logrus.Level) logrus.Hook {
+
+	hook := &levelFilterHook{
+		ChainElement: ChainElement{
+			next: next,
+		},
+		levels:  make([]logrus.Level, 0, len(levels)),
+		allowed: make(map[logrus.Level]bool, len(levels)),
+	}
+
+	for _, level := range levels {
+		if !hook.allowed[level] {
+			hook.allowed[level] = true
+			hook.levels = append(hook.levels, level)
+		}
+	}
+
+	return hook
+}
+
+// MinLevel creates a Logrus hook that fires for the given log level and the
+// levels that are more severe, like Warn, Error, Fatal and Panic for Warn
+func MinLevel(next logrus.Hook, level logrus.Level) logrus.Hook {
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, l := range logrus.AllLevels {
+		if l <= level {
+			levels = append(levels, l)
+		}
+	}
+
+	return LevelFilterHook(next, levels...)
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends the message to the next hook if its level is allowed
+func (h *levelFilterHook) Fire(entry *logrus.Entry) error {
+	if entry != nil && !h.allowed[entry.Level] {
+		return nil
+	}
+
+	return h.next.Fire(entry)
+}
+
+// Levels are the log levels that the hook fires for
+func (h *levelFilterHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Inspect describes the log levels of the hook
+func (h *levelFilterHook) Inspect() map[string]interface{} {
+	levels := make([]string, 0, len(h.levels))
+	for _, level := range h.levels {
+		levels = append(levels, level.String())
+	}
+
+	return map[string]interface{}{
+		"levels": levels,
+	}
+}
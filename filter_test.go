@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestLevelFilter_Levels(t *testing.T) {
+	testData := []struct {
+		hook     logrus.Hook
+		expected []logrus.Level
+	}{
+		// narrow down the levels of the next hook
+		{
+			LevelFilterHook(&mockCannedHook{levels: logrus.AllLevels}, logrus.ErrorLevel, logrus.WarnLevel),
+			[]logrus.Level{logrus.ErrorLevel, logrus.WarnLevel},
+		},
+		// widen the levels of the next hook
+		{
+			LevelFilterHook(&mockCannedHook{levels: []logrus.Level{logrus.ErrorLevel}}, logrus.DebugLevel),
+			[]logrus.Level{logrus.DebugLevel},
+		},
+		// duplicate levels are ignored
+		{
+			LevelFilterHook(&mockCannedHook{}, logrus.InfoLevel, logrus.InfoLevel),
+			[]logrus.Level{logrus.InfoLevel},
+		},
+		{
+			MinLevel(&mockCannedHook{}, logrus.WarnLevel),
+			[]logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel},
+		},
+		{
+			MinLevel(&mockCannedHook{}, logrus.TraceLevel),
+			logrus.AllLevels,
+		},
+	}
+
+	for i, td := range testData {
+		levels := td.hook.Levels()
+		if len(levels) != len(td.expected) {
+			t.Errorf("test [%d]: wrong levels: expected=%v, found=%v", i, td.expected, levels)
+			continue
+		}
+		for j := range levels {
+			if levels[j] != td.expected[j] {
+				t.Errorf("test [%d]: wrong levels: expected=%v, found=%v", i, td.expected, levels)
+				break
+			}
+		}
+	}
+}
+
+func TestLevelFilter_Fire(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := MinLevel(&mockHook, logrus.WarnLevel)
+
+	var sent []*logrus.Entry
+	for _, level := range logrus.AllLevels {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Level = level
+		entry.Message = "message at " + level.String()
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+		if level <= logrus.WarnLevel {
+			sent = append(sent, entry)
+		}
+	}
+
+	mockHook.compare(t, sent)
+}
+
+func TestLevelFilter_Logger(t *testing.T) {
+	var (
+		warnings mockRecordingHook
+		debug    mockRecordingHook
+	)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.TraceLevel)
+	logger.AddHook(MinLevel(&warnings, logrus.WarnLevel))
+	logger.AddHook(LevelFilterHook(&debug, logrus.DebugLevel))
+
+	logger.Out = io.Discard
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Error("error message")
+
+	if n := warnings.len(t); n != 1 {
+		t.Errorf("wrong number of messages at warning and above: expected=1, found=%d", n)
+	}
+	if n := debug.len(t); n != 1 {
+		t.Errorf("wrong number of debug messages: expected=1, found=%d", n)
+	}
+}
@@ -0,0 +1,129 @@
+package hookstest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AssertCount checks the number of messages received by the recorder
+func (r *Recorder) AssertCount(t testing.TB, n int) bool {
+	t.Helper()
+
+	if found := r.Len(); found != n {
+		t.Errorf("wrong number of messages: expected=%d, found=%d", n, found)
+		return false
+	}
+
+	return true
+}
+
+// AssertMessages checks the text of the messages received by the recorder, in order
+func (r *Recorder) AssertMessages(t testing.TB, messages ...string) bool {
+	t.Helper()
+
+	found := r.Messages()
+	if len(found) != len(messages) || (len(messages) > 0 && !reflect.DeepEqual(found, messages)) {
+		t.Errorf("wrong messages: expected=%q, found=%q", messages, found)
+		return false
+	}
+
+	return true
+}
+
+// AssertLevels checks the log levels of the messages received by the recorder, in order
+func (r *Recorder) AssertLevels(t testing.TB, levels ...logrus.Level) bool {
+	t.Helper()
+
+	entries := r.Entries()
+	found := make([]logrus.Level, 0, len(entries))
+	for _, entry := range entries {
+		found = append(found, entry.Level)
+	}
+
+	if len(found) != len(levels) || (len(levels) > 0 && !reflect.DeepEqual(found, levels)) {
+		t.Errorf("wrong levels: expected=%v, found=%v", levels, found)
+		return false
+	}
+
+	return true
+}
+
+// AssertCountAtLevel checks the number of messages of a log level received by the recorder
+func (r *Recorder) AssertCountAtLevel(t testing.TB, level logrus.Level, n int) bool {
+	t.Helper()
+
+	found := 0
+	for _, entry := range r.Entries() {
+		if entry.Level == level {
+			found++
+		}
+	}
+
+	if found != n {
+		t.Errorf("wrong number of messages at level %s: expected=%d, found=%d", level, n, found)
+		return false
+	}
+
+	return true
+}
+
+// AssertField checks the value of a field of the i-th message received by the
+// recorder, negative i counts from the last message
+func (r *Recorder) AssertField(t testing.TB, i int, key string, value interface{}) bool {
+	t.Helper()
+
+	entry, err := r.entryAt(i)
+	if err != nil {
+		t.Errorf("%s", err)
+		return false
+	}
+
+	found, ok := entry.Data[key]
+	switch {
+	case !ok:
+		t.Errorf("message [%d] has no field %q: %v", i, key, entry.Data)
+		return false
+	case !reflect.DeepEqual(found, value):
+		t.Errorf("wrong value of field %q of message [%d]: expected=%v, found=%v", key, i, value, found)
+		return false
+	}
+
+	return true
+}
+
+// AssertNoField checks that the i-th message received by the recorder does
+// not have the field, negative i counts from the last message
+func (r *Recorder) AssertNoField(t testing.TB, i int, key string) bool {
+	t.Helper()
+
+	entry, err := r.entryAt(i)
+	if err != nil {
+		t.Errorf("%s", err)
+		return false
+	}
+
+	if found, ok := entry.Data[key]; ok {
+		t.Errorf("message [%d] has field %q: %v", i, key, found)
+		return false
+	}
+
+	return true
+}
+
+// entryAt finds the i-th message, negative i counts from the last message
+func (r *Recorder) entryAt(i int) (*logrus.Entry, error) {
+	entries := r.Entries()
+
+	j := i
+	if j < 0 {
+		j += len(entries)
+	}
+	if j < 0 || j >= len(entries) {
+		return nil, fmt.Errorf("message [%d] was not received, there are %d messages", i, len(entries))
+	}
+
+	return entries[j], nil
+}
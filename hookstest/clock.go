@@ -0,0 +1,227 @@
+// Package hookstest provides test doubles for the hooks of the parent package:
+// a fake clock, sinks that record messages, fail on a script or add latency,
+// and assertions on the recorded messages
+package hookstest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	hooks "logrus-hooks.git"
+)
+
+// FakeClock is a hooks.Clock whose time moves only when the test advances it
+//
+// The timers and the sleepers of the clock wake up when the clock is advanced
+// past their deadlines. With auto advance, Sleep moves the clock forward by
+// itself instead of waiting, which makes the retries of a hook run instantly.
+type FakeClock struct {
+	sync.Mutex
+
+	now         time.Time
+	autoAdvance bool
+	timers      []*fakeTimer
+
+	// changed is closed and replaced when the set of pending timers changes
+	changed chan struct{}
+}
+
+// fakeTimer is a timer of the fake clock
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+// NewFakeClock creates a fake clock that starts at the given time
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// SetAutoAdvance makes Sleep move the clock forward instead of waiting
+func (c *FakeClock) SetAutoAdvance(on bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.autoAdvance = on
+}
+
+// Now is the current time of the clock
+func (c *FakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// Sleep waits until the clock is advanced by the duration
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.Lock()
+	if c.autoAdvance {
+		c.Unlock()
+		c.Advance(d)
+		return
+	}
+	c.Unlock()
+
+	<-c.After(d)
+}
+
+// NewTimer creates a timer that fires when the clock is advanced by the duration
+func (c *FakeClock) NewTimer(d time.Duration) hooks.Timer {
+	c.Lock()
+	defer c.Unlock()
+
+	t := &fakeTimer{
+		clock: c,
+		c:     make(chan time.Time, 1),
+	}
+	c.schedule(t, d)
+
+	return t
+}
+
+// After waits for the clock to be advanced by the duration and then sends the
+// time of the clock on the channel
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance moves the clock forward and fires the timers that are due, in the
+// order of their deadlines
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.setTime(c.now.Add(d))
+}
+
+// Set moves the clock to the given time, which can not be in the past of the clock
+func (c *FakeClock) Set(t time.Time) {
+	c.Lock()
+	defer c.Unlock()
+
+	if t.After(c.now) {
+		c.setTime(t)
+	}
+}
+
+// Pending is the number of timers and sleepers that wait for the clock
+func (c *FakeClock) Pending() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n timers and sleepers wait for the clock,
+// so that the test can advance the clock after the code under test is ready
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.Unlock()
+
+		if pending >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+// setTime moves the clock and fires the timers that are due
+// note: this function must be called with the clock's mutex locked
+func (c *FakeClock) setTime(t time.Time) {
+	c.now = t
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	n := 0
+	for _, timer := range c.timers {
+		if timer.deadline.After(c.now) {
+			c.timers[n] = timer
+			n++
+			continue
+		}
+		timer.fire(c.now)
+	}
+
+	if n < len(c.timers) {
+		c.timers = c.timers[:n]
+		c.notify()
+	}
+}
+
+// schedule adds a timer to the pending timers, or fires it right away if
+// the duration is not positive
+// note: this function must be called with the clock's mutex locked
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		t.fire(c.now)
+		return
+	}
+
+	c.timers = append(c.timers, t)
+	c.notify()
+}
+
+// unschedule removes a timer from the pending timers
+// note: this function must be called with the clock's mutex locked
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			c.notify()
+			return true
+		}
+	}
+
+	return false
+}
+
+// notify wakes up the tests that wait for the pending timers to change
+// note: this function must be called with the clock's mutex locked
+func (c *FakeClock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// C is the channel on which the time is sent when the timer fires
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop prevents the timer from firing
+func (t *fakeTimer) Stop() bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	return t.clock.unschedule(t)
+}
+
+// Reset changes the timer to fire when the clock is advanced by the duration
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.Lock()
+	defer t.clock.Unlock()
+
+	active := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+
+	return active
+}
+
+// fire sends the time on the channel of the timer, unless the previous
+// time was not received yet
+func (t *fakeTimer) fire(now time.Time) {
+	select {
+	case t.c <- now:
+	default:
+	}
+}
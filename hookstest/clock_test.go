@@ -0,0 +1,99 @@
+package hookstest
+
+import (
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestFakeClock_Timers(t *testing.T) {
+	clock := NewFakeClock(testStart)
+
+	late := clock.NewTimer(2 * time.Second)
+	early := clock.After(time.Second)
+	stopped := clock.NewTimer(time.Second)
+
+	if !stopped.Stop() {
+		t.Errorf("stop of a pending timer returned false")
+	}
+	if clock.Pending() != 2 {
+		t.Errorf("wrong number of pending timers: %d", clock.Pending())
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-early:
+		if !now.Equal(testStart.Add(time.Second)) {
+			t.Errorf("wrong time of the timer: %s", now)
+		}
+	default:
+		t.Errorf("timer did not fire")
+	}
+	select {
+	case <-late.C():
+		t.Errorf("timer fired too early")
+	case <-stopped.C():
+		t.Errorf("stopped timer fired")
+	default:
+	}
+
+	if late.Reset(time.Second) != true {
+		t.Errorf("reset of a pending timer returned false")
+	}
+	clock.Set(testStart.Add(2 * time.Second))
+	select {
+	case <-late.C():
+	default:
+		t.Errorf("reset timer did not fire")
+	}
+
+	if clock.Pending() != 0 || !clock.Now().Equal(testStart.Add(2*time.Second)) {
+		t.Errorf("wrong state of the clock: pending=%d, now=%s", clock.Pending(), clock.Now())
+	}
+
+	// timers without a duration fire right away
+	select {
+	case <-clock.After(0):
+	default:
+		t.Errorf("timer without a duration did not fire")
+	}
+}
+
+func TestFakeClock_Sleep(t *testing.T) {
+	clock := NewFakeClock(testStart)
+
+	done := make(chan struct{})
+	go func() {
+		clock.Sleep(time.Minute)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(30 * time.Second)
+	select {
+	case <-done:
+		t.Fatalf("sleep ended too early")
+	default:
+	}
+
+	clock.Advance(30 * time.Second)
+	<-done
+}
+
+func TestFakeClock_AutoAdvance(t *testing.T) {
+	clock := NewFakeClock(testStart)
+	clock.SetAutoAdvance(true)
+
+	timer := clock.NewTimer(time.Second)
+	clock.Sleep(time.Minute)
+
+	if !clock.Now().Equal(testStart.Add(time.Minute)) {
+		t.Errorf("clock was not advanced by the sleep: %s", clock.Now())
+	}
+	select {
+	case <-timer.C():
+	default:
+		t.Errorf("timer did not fire during the sleep")
+	}
+}
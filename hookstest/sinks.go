@@ -0,0 +1,228 @@
+package hookstest
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	hooks "logrus-hooks.git"
+)
+
+// Recorder is a sink that keeps copies of all messages in the order they
+// were received, repeated messages included
+type Recorder struct {
+	sync.Mutex
+
+	levels  []logrus.Level
+	entries []*logrus.Entry
+}
+
+// NewRecorder creates a recorder that fires for the given log levels, or
+// for all levels when none are given
+func NewRecorder(levels ...logrus.Level) *Recorder {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+
+	return &Recorder{levels: levels}
+}
+
+// Fire keeps a copy of the message
+func (r *Recorder) Fire(entry *logrus.Entry) error {
+	r.Lock()
+	defer r.Unlock()
+
+	r.entries = append(r.entries, copyEntry(entry))
+
+	return nil
+}
+
+// Levels are the log levels of the recorder
+func (r *Recorder) Levels() []logrus.Level {
+	return r.levels
+}
+
+// Entries lists the messages in the order they were received
+func (r *Recorder) Entries() []*logrus.Entry {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]*logrus.Entry(nil), r.entries...)
+}
+
+// Messages lists the text of the messages in the order they were received
+func (r *Recorder) Messages() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	messages := make([]string, 0, len(r.entries))
+	for _, entry := range r.entries {
+		messages = append(messages, entry.Message)
+	}
+
+	return messages
+}
+
+// Len is the number of messages received
+func (r *Recorder) Len() int {
+	r.Lock()
+	defer r.Unlock()
+
+	return len(r.entries)
+}
+
+// Reset forgets the messages received so far
+func (r *Recorder) Reset() {
+	r.Lock()
+	defer r.Unlock()
+
+	r.entries = nil
+}
+
+// ScriptedHook returns a scripted sequence of results, one per message, and
+// passes the messages that succeed on to the next hook
+type ScriptedHook struct {
+	sync.Mutex
+
+	next   logrus.Hook
+	script []error
+	calls  int
+}
+
+// ErrScripted is the error of the scripted failures created by FailTimes
+var ErrScripted = errors.New("scripted failure")
+
+// FailTimes is a script that fails n times with the error, or with
+// ErrScripted if the error is nil
+func FailTimes(n int, err error) []error {
+	if err == nil {
+		err = ErrScripted
+	}
+
+	script := make([]error, n)
+	for i := range script {
+		script[i] = err
+	}
+
+	return script
+}
+
+// NewScriptedHook creates a hook that returns the results of the script in
+// order, the messages after the end of the script succeed
+//
+// The messages that succeed are passed on to the next hook, if there is one.
+func NewScriptedHook(next logrus.Hook, script ...error) *ScriptedHook {
+	return &ScriptedHook{
+		next:   next,
+		script: script,
+	}
+}
+
+// Fire returns the next result of the script
+func (h *ScriptedHook) Fire(entry *logrus.Entry) error {
+	h.Lock()
+	var err error
+	if h.calls < len(h.script) {
+		err = h.script[h.calls]
+	}
+	h.calls++
+	h.Unlock()
+
+	if err != nil || h.next == nil {
+		return err
+	}
+
+	return h.next.Fire(entry)
+}
+
+// Levels are the log levels of the next hook, or all levels
+func (h *ScriptedHook) Levels() []logrus.Level {
+	if h.next == nil {
+		return logrus.AllLevels
+	}
+
+	return h.next.Levels()
+}
+
+// Next is the hook that receives the messages that succeed
+func (h *ScriptedHook) Next() logrus.Hook {
+	return h.next
+}
+
+// Calls is the number of times the hook was fired
+func (h *ScriptedHook) Calls() int {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.calls
+}
+
+// LatencyHook delays every message before it is passed on to the next hook
+type LatencyHook struct {
+	next    logrus.Hook
+	latency func() time.Duration
+	clock   hooks.Clock
+}
+
+// NewLatencyHook creates a hook that delays every message by the latency
+//
+// The delay is a sleep of the clock, which is the system clock when nil.
+func NewLatencyHook(next logrus.Hook, latency time.Duration, clock hooks.Clock) *LatencyHook {
+	return NewVariableLatencyHook(next, func() time.Duration { return latency }, clock)
+}
+
+// NewVariableLatencyHook creates a hook that delays every message by the
+// duration returned by the latency function
+func NewVariableLatencyHook(next logrus.Hook, latency func() time.Duration, clock hooks.Clock) *LatencyHook {
+	if clock == nil {
+		clock = hooks.SystemClock
+	}
+
+	return &LatencyHook{
+		next:    next,
+		latency: latency,
+		clock:   clock,
+	}
+}
+
+// Fire delays the message and passes it on to the next hook
+func (h *LatencyHook) Fire(entry *logrus.Entry) error {
+	h.clock.Sleep(h.latency())
+
+	if h.next == nil {
+		return nil
+	}
+
+	return h.next.Fire(entry)
+}
+
+// Levels are the log levels of the next hook, or all levels
+func (h *LatencyHook) Levels() []logrus.Level {
+	if h.next == nil {
+		return logrus.AllLevels
+	}
+
+	return h.next.Levels()
+}
+
+// Next is the hook that receives the delayed messages
+func (h *LatencyHook) Next() logrus.Hook {
+	return h.next
+}
+
+// copyEntry makes a copy of a log entry that is not affected by changes to
+// the original entry
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	if entry == nil {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	dup := *entry
+	dup.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		dup.Data[k] = v
+	}
+
+	return &dup
+}
@@ -0,0 +1,151 @@
+package hookstest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newEntry(level logrus.Level, message string, fields logrus.Fields) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(fields)
+	entry.Level = level
+	entry.Message = message
+
+	return entry
+}
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder()
+
+	entry := newEntry(logrus.InfoLevel, "hello", logrus.Fields{"user": "alice"})
+	_ = rec.Fire(entry)
+	_ = rec.Fire(entry)
+	_ = rec.Fire(newEntry(logrus.ErrorLevel, "failed", nil))
+
+	// changes of the original message do not affect the copy
+	entry.Data["user"] = "bob"
+
+	rec.AssertCount(t, 3)
+	rec.AssertMessages(t, "hello", "hello", "failed")
+	rec.AssertLevels(t, logrus.InfoLevel, logrus.InfoLevel, logrus.ErrorLevel)
+	rec.AssertCountAtLevel(t, logrus.InfoLevel, 2)
+	rec.AssertField(t, 0, "user", "alice")
+	rec.AssertNoField(t, -1, "user")
+
+	if levels := NewRecorder(logrus.ErrorLevel).Levels(); len(levels) != 1 {
+		t.Errorf("wrong levels of the recorder: %v", levels)
+	}
+
+	rec.Reset()
+	rec.AssertCount(t, 0)
+	rec.AssertMessages(t)
+}
+
+func TestRecorder_NilEntry(t *testing.T) {
+	rec := NewRecorder()
+	if err := rec.Fire(nil); err != nil {
+		t.Fatalf("recorder failed: %s", err)
+	}
+	rec.AssertCount(t, 1)
+}
+
+func TestRecorder_Assertions(t *testing.T) {
+	rec := NewRecorder()
+	_ = rec.Fire(newEntry(logrus.InfoLevel, "hello", logrus.Fields{"user": "alice"}))
+
+	for name, assert := range map[string]func(testing.TB) bool{
+		"count":    func(tb testing.TB) bool { return rec.AssertCount(tb, 2) },
+		"messages": func(tb testing.TB) bool { return rec.AssertMessages(tb, "bye") },
+		"levels":   func(tb testing.TB) bool { return rec.AssertLevels(tb, logrus.WarnLevel) },
+		"at level": func(tb testing.TB) bool { return rec.AssertCountAtLevel(tb, logrus.InfoLevel, 0) },
+		"field":    func(tb testing.TB) bool { return rec.AssertField(tb, 0, "user", "bob") },
+		"missing":  func(tb testing.TB) bool { return rec.AssertField(tb, 0, "host", "db") },
+		"no field": func(tb testing.TB) bool { return rec.AssertNoField(tb, 0, "user") },
+		"index":    func(tb testing.TB) bool { return rec.AssertField(tb, 1, "user", "alice") },
+	} {
+		tb := &recordingTB{TB: t}
+		if assert(tb) || !tb.failed {
+			t.Errorf("assertion %q did not fail", name)
+		}
+	}
+}
+
+// recordingTB notes the failures instead of reporting them
+type recordingTB struct {
+	testing.TB
+	failed bool
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(string, ...interface{}) {
+	tb.failed = true
+}
+
+func TestScriptedHook(t *testing.T) {
+	rec := NewRecorder()
+	errOther := errors.New("other")
+	hook := NewScriptedHook(rec, append(FailTimes(2, nil), nil, errOther)...)
+
+	expected := []error{ErrScripted, ErrScripted, nil, errOther, nil, nil}
+	for i, want := range expected {
+		if err := hook.Fire(newEntry(logrus.InfoLevel, "msg", nil)); !errors.Is(err, want) || (want == nil && err != nil) {
+			t.Errorf("wrong result of call [%d]: expected=%v, found=%v", i, want, err)
+		}
+	}
+
+	if calls := hook.Calls(); calls != len(expected) {
+		t.Errorf("wrong number of calls: expected=%d, found=%d", len(expected), calls)
+	}
+	rec.AssertCount(t, 3)
+
+	if hook.Next() != rec {
+		t.Errorf("wrong next hook")
+	}
+	if err := NewScriptedHook(nil).Fire(nil); err != nil {
+		t.Errorf("scripted hook without next failed: %s", err)
+	}
+}
+
+func TestLatencyHook(t *testing.T) {
+	clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	rec := NewRecorder()
+	hook := NewLatencyHook(rec, time.Second, clock)
+
+	done := make(chan error)
+	go func() {
+		done <- hook.Fire(newEntry(logrus.InfoLevel, "slow", nil))
+	}()
+
+	clock.BlockUntil(1)
+	rec.AssertCount(t, 0)
+
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("latency hook failed: %s", err)
+	}
+	rec.AssertMessages(t, "slow")
+}
+
+func TestVariableLatencyHook(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	clock.SetAutoAdvance(true)
+
+	latency := time.Duration(0)
+	hook := NewVariableLatencyHook(nil, func() time.Duration {
+		latency += time.Second
+		return latency
+	}, clock)
+
+	for i := 0; i < 3; i++ {
+		_ = hook.Fire(nil)
+	}
+
+	// the delays are 1s, 2s and 3s
+	if elapsed := clock.Now().Sub(start); elapsed != 6*time.Second {
+		t.Errorf("wrong total latency: expected=6s, found=%s", elapsed)
+	}
+}
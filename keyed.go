@@ -0,0 +1,323 @@
+package hooks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// default limits on the number of keys that are tracked
+	defaultMaxKeys = 1024
+	defaultKeyTTL  = 10 * time.Minute
+)
+
+// keyedRateLimitHook is a Logrus hook that enforces separate rate limits on
+// groups of logged messages
+type keyedRateLimitHook struct {
+	sync.Mutex
+
+	ChainImpl
+	conf rateLimit
+	key  KeyFunc
+
+	// keys holds the rate limiters of the recently seen keys
+	keys *keyCache[*limiterSet]
+
+	// global is the limit on all messages, nil when there is no such limit
+	global *rate.Limiter
+
+	// evicted holds the reports of the messages dropped under the keys that
+	// were evicted, they are sent ahead of the next message
+	evicted []*logrus.Entry
+}
+
+// KeyFunc derives the key of a log entry that identifies the group of
+// messages the entry belongs to
+type KeyFunc func(entry *logrus.Entry) string
+
+// KeyFields makes the key of a log entry out of the values of its fields
+func KeyFields(names ...string) KeyFunc {
+	return func(entry *logrus.Entry) string {
+		var key strings.Builder
+		for i, name := range names {
+			if i > 0 {
+				key.WriteByte('|')
+			}
+			if value, found := entry.Data[name]; found {
+				fmt.Fprint(&key, value)
+			}
+		}
+
+		return key.String()
+	}
+}
+
+// KeyMessage makes the message of a log entry its key
+func KeyMessage() KeyFunc {
+	return func(entry *logrus.Entry) string {
+		return entry.Message
+	}
+}
+
+// KeyCaller makes the call site of a log entry its key
+//
+// The logger must be configured to report the caller, otherwise all entries
+// will have the same empty key.
+func KeyCaller() KeyFunc {
+	return func(entry *logrus.Entry) string {
+		if entry.Caller == nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+	}
+}
+
+// KeyGoroutine makes the goroutine that logs an entry its key
+//
+// The hooks must run on the goroutine of the logger for this key to be
+// meaningful, so it can not be used after an async hook.
+func KeyGoroutine() KeyFunc {
+	return func(*logrus.Entry) string {
+		return goroutineID()
+	}
+}
+
+// goroutineID reads the id of the current goroutine from its stack trace,
+// which starts with "goroutine 42 [running]:"
+func goroutineID() string {
+	var buf [64]byte
+	stack := buf[:runtime.Stack(buf[:], false)]
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i > 0 {
+		return string(stack[:i])
+	}
+
+	return ""
+}
+
+// constructor --------------------------------------------------------
+
+// MaxKeys sets the maximum number of keys that the keyed rate limit hook
+// keeps track of, the least recently used keys are evicted first
+func MaxKeys(n int) RateLimitOption {
+	return func(conf *rateLimit) {
+		if n >= 0 {
+			conf.maxKeys = n
+		}
+	}
+}
+
+// KeyTTL sets the time after which the keyed rate limit hook forgets the
+// keys that were not used
+func KeyTTL(d time.Duration) RateLimitOption {
+	return func(conf *rateLimit) {
+		if d >= 0 {
+			conf.keyTTL = d
+		}
+	}
+}
+
+// GlobalLimit sets a limit on all messages logged by the keyed rate limit
+// hook, on top of the limits of the individual keys
+func GlobalLimit(perSecond, burst int) RateLimitOption {
+	return func(conf *rateLimit) {
+		conf.global = &levelLimit{
+			limitPeSecond: perSecond,
+			burst:         burst,
+		}
+	}
+}
+
+// KeyedRateLimitHook creates a Logrus hook that enforces separate rate limits
+// on the groups of logged messages that have the same key
+//
+// The limit options PerSecond, Burst, LevelLimit and Exempt apply to each key.
+func KeyedRateLimitHook(next logrus.Hook, key KeyFunc, opts ...RateLimitOption) logrus.Hook {
+
+	// default configuration
+	conf := rateLimit{
+		limitPeSecond: defaultRatePerSecond,
+		burst:         defaultBurst,
+		maxKeys:       defaultMaxKeys,
+		keyTTL:        defaultKeyTTL,
+	}
+	conf = conf.update(opts)
+
+	hook := &keyedRateLimitHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		conf: conf,
+		key:  key,
+		keys: newKeyCache[*limiterSet](conf.maxKeys, conf.keyTTL),
+	}
+	hook.keys.onEvict = hook.evict
+
+	if conf.global != nil {
+		hook.global = rate.NewLimiter(
+			rate.Limit(conf.global.limitPeSecond),
+			conf.global.burst,
+		)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire delivers the message to the next hook if the limit of its key permits
+func (h *keyedRateLimitHook) Fire(entry *logrus.Entry) error {
+
+	key := ""
+	if entry != nil {
+		key = h.key(entry)
+	}
+
+	conf, set, global, evicted := h.limiters(key)
+
+	// the reports of the evicted keys go ahead of the message
+	reportErr := h.report(evicted)
+	err := h.limit(conf, set, global, entry, key)
+	if reportErr != nil {
+		return errors.Join(reportErr, err)
+	}
+
+	return err
+}
+
+// limit delivers the message if both the limit of its key and the global
+// limit permit
+func (h *keyedRateLimitHook) limit(conf *rateLimit, set *limiterSet, global *rate.Limiter, entry *logrus.Entry, key string) error {
+	limiter := set.forEntry(entry)
+	if limiter == nil {
+		// the level of the message is exempt from rate limits
+		return conf.exempt(h.next, entry)
+	}
+
+	limiters := []*rate.Limiter{limiter}
+	if global != nil {
+		limiters = append(limiters, global)
+	}
+	if err := conf.allow(entry, limiters...); err != nil {
+		return conf.suppress(set, entry, err)
+	}
+
+	return conf.deliver(h.next, set, entry, logrus.Fields{"key": key})
+}
+
+// Flush reports the messages that were dropped under all keys since their
+// last reports
+func (h *keyedRateLimitHook) Flush() error {
+	h.Lock()
+	summaries := h.evicted
+	h.evicted = nil
+	now := h.conf.now()
+	h.keys.each(func(key string, set *limiterSet) {
+		if summary := set.suppressed.summary(nil, now); summary != nil {
+			summary.Data["key"] = key
+			summaries = append(summaries, summary)
+		}
+	})
+	h.Unlock()
+
+	return h.report(summaries)
+}
+
+// Reconfigure changes the rate limits of all keys and the global limit
+func (h *keyedRateLimitHook) Reconfigure(opts ...RateLimitOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.conf = h.conf.update(opts)
+
+	h.keys.resize(h.conf.maxKeys, h.conf.keyTTL)
+	h.keys.replace(func(set *limiterSet) *limiterSet {
+		return set.reconfigure(&h.conf)
+	})
+
+	switch {
+	case h.conf.global == nil:
+		h.global = nil
+	case h.global == nil:
+		h.global = rate.NewLimiter(
+			rate.Limit(h.conf.global.limitPeSecond),
+			h.conf.global.burst,
+		)
+	default:
+		h.global.SetLimit(rate.Limit(h.conf.global.limitPeSecond))
+		h.global.SetBurst(h.conf.global.burst)
+	}
+
+	return nil
+}
+
+// Inspect describes the rate limits of the hook and the number of tracked keys
+func (h *keyedRateLimitHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	state := h.conf.inspect()
+	state["max_keys"] = h.conf.maxKeys
+	state["key_ttl"] = h.conf.keyTTL.String()
+	state["keys"] = h.keys.len()
+	if h.conf.global != nil {
+		state["global"] = map[string]interface{}{
+			"per_second": h.conf.global.limitPeSecond,
+			"burst":      h.conf.global.burst,
+		}
+	}
+
+	return state
+}
+
+// limiters finds the rate limiters of the key of a log entry, together with
+// the configuration and the global limiter that go with them, and takes the
+// reports of the evicted keys
+func (h *keyedRateLimitHook) limiters(key string) (*rateLimit, *limiterSet, *rate.Limiter, []*logrus.Entry) {
+	h.Lock()
+	defer h.Unlock()
+
+	now := h.conf.now()
+	h.keys.expire(now)
+
+	set, found := h.keys.get(key, now)
+	if !found {
+		set = newLimiterSet(&h.conf)
+		h.keys.add(key, set, now)
+	}
+
+	conf, evicted := h.conf, h.evicted
+	h.evicted = nil
+
+	return &conf, set, h.global, evicted
+}
+
+// evict keeps the report of the messages dropped under an evicted key, the
+// hook must be locked
+func (h *keyedRateLimitHook) evict(key string, set *limiterSet) {
+	if summary := set.suppressed.summary(nil, h.conf.now()); summary != nil {
+		summary.Data["key"] = key
+		h.evicted = append(h.evicted, summary)
+	}
+}
+
+// report sends the reports of dropped messages to the next hook
+func (h *keyedRateLimitHook) report(summaries []*logrus.Entry) error {
+	var errs []error
+	for _, summary := range summaries {
+		errs = append(errs, h.next.Fire(summary))
+	}
+
+	return errors.Join(errs...)
+}
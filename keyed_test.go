@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestKeyFunc(t *testing.T) {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		"tenant": "acme",
+		"user":   42,
+	})
+	entry.Message = "test message"
+	entry.Caller = &runtime.Frame{File: "main.go", Line: 7}
+
+	testData := []struct {
+		key      KeyFunc
+		expected string
+	}{
+		{KeyFields("tenant"), "acme"},
+		{KeyFields("tenant", "user"), "acme|42"},
+		{KeyFields("tenant", "missing", "user"), "acme||42"},
+		{KeyMessage(), "test message"},
+		{KeyCaller(), "main.go:7"},
+	}
+
+	for i, td := range testData {
+		if key := td.key(entry); key != td.expected {
+			t.Errorf("wrong key at [test=%d]: expected=%q, found=%q", i, td.expected, key)
+		}
+	}
+}
+
+func TestKeyedRateLimit(t *testing.T) {
+	testData := []int{
+		1, 10, 25,
+	}
+
+	for _, td := range testData {
+		hook := KeyedRateLimitHook(
+			&mockCannedHook{},
+			KeyFields("tenant"),
+			PerSecond(1),
+			Burst(td),
+		)
+
+		t.Run(fmt.Sprintf("burst of %d", td), func(t *testing.T) {
+			for _, tenant := range []string{"acme", "globex", "initech"} {
+				entry := logrus.NewEntry(logrus.StandardLogger()).WithField("tenant", tenant)
+
+				for i := 0; i < td; i++ {
+					if err := hook.Fire(entry); err != nil {
+						t.Fatalf("tenant %s was rate limited too early after %d times: %s", tenant, i, err)
+					}
+				}
+
+				if hook.Fire(entry) == nil {
+					t.Fatalf("tenant %s was not limited after %d times", tenant, td)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyedRateLimit_Global(t *testing.T) {
+	hook := KeyedRateLimitHook(
+		&mockCannedHook{},
+		KeyMessage(),
+		PerSecond(1),
+		Burst(1),
+		GlobalLimit(1, 5),
+	)
+
+	for i := 0; i < 5; i++ {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Message = fmt.Sprintf("test message: %d", i)
+
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("rate limited too early after %d times: %s", i, err)
+		}
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "one message too many"
+	if hook.Fire(entry) == nil {
+		t.Fatalf("hook was not limited by the global limit")
+	}
+
+	// the key keeps the token that the global limit did not let it use
+	set, found := hook.(*keyedRateLimitHook).keys.peek(entry.Message)
+	if !found {
+		t.Fatalf("the key of the limited message is not tracked")
+	}
+	if tokens := set.defaultLimiter.TokensAt(time.Now()); tokens < 1 {
+		t.Errorf("the global limit took the token of the key, %.2f tokens left", tokens)
+	}
+}
+
+func TestKeyedRateLimit_MaxKeys(t *testing.T) {
+	testData := []int{
+		1, 10, 25, 50, 100,
+	}
+
+	for _, td := range testData {
+		hook := KeyedRateLimitHook(
+			&mockCannedHook{},
+			KeyMessage(),
+			MaxKeys(td),
+		)
+
+		theHook, ok := hook.(*keyedRateLimitHook)
+		if !ok {
+			t.Fatalf("test hook is not of the expected keyedRateLimitHook type: %v", hook)
+		}
+
+		for i := 0; i < 2*td; i++ {
+			entry := logrus.NewEntry(logrus.StandardLogger())
+			entry.Message = fmt.Sprintf("test message: %d", i)
+
+			if err := hook.Fire(entry); err != nil {
+				t.Fatalf("first message of key [%d] was rate limited: %s", i, err)
+			}
+		}
+
+		if n := theHook.keys.len(); n != td {
+			t.Errorf("number of tracked keys is wrong: expected=%d, found=%d", td, n)
+		}
+	}
+}
+
+func TestKeyedRateLimit_Evicted(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := KeyedRateLimitHook(
+		&mockHook,
+		KeyFields("tenant"),
+		PerSecond(1),
+		Burst(1),
+		MaxKeys(1),
+		Summarize(),
+	)
+
+	fire := func(tenant string, n int) {
+		for i := 0; i < n; i++ {
+			entry := newTestEntry(logrus.InfoLevel, fmt.Sprintf("message %d of %s", i, tenant),
+				logrus.Fields{"tenant": tenant})
+			_ = hook.Fire(entry)
+		}
+	}
+	summaries := func() map[interface{}]interface{} {
+		found := map[interface{}]interface{}{}
+		mockHook.messages.Range(func(key, value interface{}) bool {
+			if msg := value.(*logrus.Entry); msg.Data["suppressed"] != nil {
+				found[msg.Data["key"]] = msg.Data["suppressed"]
+			}
+			return true
+		})
+		return found
+	}
+
+	// the new key evicts the old one, which reports its dropped messages
+	fire("acme", 3)
+	fire("globex", 2)
+	if found := summaries(); len(found) != 1 || found["acme"] != 2 {
+		t.Fatalf("evicted key did not report its dropped messages: %v", found)
+	}
+
+	// flush reports the keys that are still tracked
+	mockHook.reset(t)
+	if err := hook.(Flusher).Flush(); err != nil {
+		t.Fatalf("failed to flush the keyed rate limit hook: %s", err)
+	}
+	if found := summaries(); len(found) != 1 || found["globex"] != 1 {
+		t.Fatalf("flush did not report the dropped messages: %v", found)
+	}
+}
@@ -1,7 +1,13 @@
 package hooks
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
@@ -15,13 +21,59 @@ const (
 
 // retryHook is a Logrus hook that enforces a rate limit on the logged messages
 type rareLimitHook struct {
+	sync.RWMutex
+
 	ChainImpl
-	limiter *rate.Limiter
+	conf     rateLimit
+	limiters *limiterSet
 }
 
 type rateLimit struct {
 	limitPeSecond int
 	burst         int
+
+	// levels holds the limits of the log levels that do not use the default
+	// limit, levels that share a limit point to the same value and exempt
+	// levels are set to nil
+	levels map[logrus.Level]*levelLimit
+
+	// wait makes the hook block until the message can be sent instead of
+	// dropping it, maxWait puts an upper bound on the blocking time
+	wait    bool
+	maxWait time.Duration
+
+	// summarize makes the hook report the dropped messages
+	summarize bool
+
+	// metrics receives the events of the hook under the name of the stage
+	metrics Metrics
+	stage   string
+
+	// clock is the source of time of the limiters
+	clock Clock
+
+	// options of the keyed rate limit hook
+	maxKeys int
+	keyTTL  time.Duration
+	global  *levelLimit
+}
+
+// levelLimit is the rate limit of a group of log levels
+type levelLimit struct {
+	limitPeSecond int
+	burst         int
+}
+
+// limiterSet holds the rate limiters that apply to the different log levels
+type limiterSet struct {
+	defaultLimiter *rate.Limiter
+
+	// levels are the limiters of the levels that do not use the default
+	// limiter, the limiters of exempt levels are nil
+	levels map[logrus.Level]*rate.Limiter
+
+	// suppressed counts the messages dropped by the limiters
+	suppressed *suppression
 }
 
 // constructor --------------------------------------------------------
@@ -43,6 +95,74 @@ func Burst(n int) RateLimitOption {
 	}
 }
 
+// LevelLimit sets a separate rate limit for a group of log levels
+//
+// The levels of the group share one limit, so that messages of one group
+// do not take up the budget of the other groups. Levels that are not part
+// of any group use the default limit.
+func LevelLimit(perSecond, burst int, levels ...logrus.Level) RateLimitOption {
+	return func(conf *rateLimit) {
+		limit := &levelLimit{
+			limitPeSecond: perSecond,
+			burst:         burst,
+		}
+		for _, level := range levels {
+			conf.setLevel(level, limit)
+		}
+	}
+}
+
+// Exempt lets the messages of the given log levels pass without any rate limit
+func Exempt(levels ...logrus.Level) RateLimitOption {
+	return func(conf *rateLimit) {
+		for _, level := range levels {
+			conf.setLevel(level, nil)
+		}
+	}
+}
+
+// Summarize makes the hook count the messages it dropped and report them
+//
+// The report is a log entry that is sent to the next hook right before the
+// first message that is permitted after some messages were dropped, like
+// "suppressed 1532 entries (1500 warning, 32 error) in the last 10s".
+func Summarize() RateLimitOption {
+	return func(conf *rateLimit) {
+		conf.summarize = true
+	}
+}
+
+// RateLimitMetrics sets the metrics that receive the events of the hook
+func RateLimitMetrics(m Metrics, stage string) RateLimitOption {
+	return func(conf *rateLimit) {
+		conf.metrics = m
+		conf.stage = stage
+	}
+}
+
+// RateLimitClock sets the clock that the rate limiters use
+func RateLimitClock(c Clock) RateLimitOption {
+	return func(conf *rateLimit) {
+		conf.clock = c
+	}
+}
+
+// Wait makes the hook block the caller until the message fits in the rate limit
+//
+// The wait is bound by the context of the log entry, if there is one, and
+// by maxWait when it is greater than zero. A zero maxWait makes the wait
+// unbounded, the callers block for as long as it takes when the entries have
+// no context deadline. The message is dropped with an error right away when
+// the required delay is known to exceed these bounds.
+func Wait(maxWait time.Duration) RateLimitOption {
+	return func(conf *rateLimit) {
+		conf.wait = true
+		if maxWait >= 0 {
+			conf.maxWait = maxWait
+		}
+	}
+}
+
 // RateLimitHook creates a Logrus hook that enforces a rate limit on the logged messages
 func RateLimitHook(next logrus.Hook, opts ...RateLimitOption) logrus.Hook {
 
@@ -51,9 +171,7 @@ func RateLimitHook(next logrus.Hook, opts ...RateLimitOption) logrus.Hook {
 		limitPeSecond: defaultRatePerSecond,
 		burst:         defaultBurst,
 	}
-	for _, opt := range opts {
-		opt(&conf)
-	}
+	conf = conf.update(opts)
 
 	hook := &rareLimitHook{
 		ChainImpl: ChainImpl{
@@ -61,10 +179,8 @@ func RateLimitHook(next logrus.Hook, opts ...RateLimitOption) logrus.Hook {
 				next: next,
 			},
 		},
-		limiter: rate.NewLimiter(
-			rate.Limit(conf.limitPeSecond),
-			conf.burst,
-		),
+		conf:     conf,
+		limiters: newLimiterSet(&conf),
 	}
 
 	return hook
@@ -75,10 +191,339 @@ func RateLimitHook(next logrus.Hook, opts ...RateLimitOption) logrus.Hook {
 // Fire makes multiple attempts to deliver the message to the next hook
 func (h *rareLimitHook) Fire(entry *logrus.Entry) error {
 
-	if !h.limiter.Allow() {
-		return fmt.Errorf("rate limit [%f/sec, burst=%d] exceeded",
-			h.limiter.Limit(), h.limiter.Burst())
+	h.RLock()
+	conf, limiters := h.conf, h.limiters
+	h.RUnlock()
+
+	limiter := limiters.forEntry(entry)
+	if limiter == nil {
+		// the level of the message is exempt from rate limits
+		return conf.exempt(h.next, entry)
+	}
+
+	if err := conf.allow(entry, limiter); err != nil {
+		return conf.suppress(limiters, entry, err)
+	}
+
+	return conf.deliver(h.next, limiters, entry)
+}
+
+// Reconfigure changes the rate limits, the default limit keeps the record
+// of the recent messages while the limits of the log levels start over
+func (h *rareLimitHook) Reconfigure(opts ...RateLimitOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	h.conf = h.conf.update(opts)
+	h.limiters = h.limiters.reconfigure(&h.conf)
+
+	return nil
+}
+
+// Flush reports the messages that were dropped since the last report
+func (h *rareLimitHook) Flush() error {
+	h.RLock()
+	conf, limiters := h.conf, h.limiters
+	h.RUnlock()
+
+	if summary := limiters.suppressed.summary(nil, conf.now()); summary != nil {
+		return h.next.Fire(summary)
+	}
+
+	return nil
+}
+
+// allow checks the message against the limiters, in wait mode it blocks
+// until all of them permit one more message
+//
+// A message takes one token from each of the limiters or none at all, the
+// tokens reserved on the limiters that permit the message are returned when
+// another limiter denies it.
+func (conf *rateLimit) allow(entry *logrus.Entry, limiters ...*rate.Limiter) error {
+
+	if !conf.wait {
+		now := conf.now()
+		reserved := make([]*rate.Reservation, 0, len(limiters))
+		for _, limiter := range limiters {
+			r := limiter.ReserveN(now, 1)
+			if r.OK() && r.DelayFrom(now) == 0 {
+				reserved = append(reserved, r)
+				continue
+			}
+
+			cancelAt(append(reserved, r), now)
+			return &RateLimitError{
+				Limit: float64(limiter.Limit()),
+				Burst: limiter.Burst(),
+				Delay: delayAt(limiter, now),
+			}
+		}
+		return nil
+	}
+
+	start := conf.now()
+	limiter, err := conf.waitFor(entry, limiters)
+	traceEvent(entry, "logrus.hook.ratelimit.wait",
+		Attribute{Key: "logrus_hooks.stage", Value: conf.stage},
+		Attribute{Key: "logrus_hooks.wait_ms", Value: conf.now().Sub(start).Milliseconds()},
+	)
+	if err != nil {
+		return &RateLimitError{
+			Limit: float64(limiter.Limit()),
+			Burst: limiter.Burst(),
+			Err:   err,
+		}
+	}
+
+	return nil
+}
+
+// cancelAt returns the tokens of the reservations to their limiters
+func cancelAt(reserved []*rate.Reservation, now time.Time) {
+	for _, r := range reserved {
+		r.CancelAt(now)
+	}
+}
+
+// Inspect describes the rate limits of the hook
+func (h *rareLimitHook) Inspect() map[string]interface{} {
+	h.RLock()
+	defer h.RUnlock()
+
+	state := h.conf.inspect()
+	state["tokens"] = h.limiters.defaultLimiter.TokensAt(h.conf.now())
+
+	return state
+}
+
+// inspect describes the configured rate limits
+func (conf *rateLimit) inspect() map[string]interface{} {
+	state := map[string]interface{}{
+		"per_second": conf.limitPeSecond,
+		"burst":      conf.burst,
+		"wait":       conf.wait,
+		"max_wait":   conf.maxWait.String(),
+		"summarize":  conf.summarize,
+	}
+
+	exempt := []string{}
+	levelLimits := map[string]interface{}{}
+	for level, limit := range conf.levels {
+		if limit == nil {
+			exempt = append(exempt, level.String())
+			continue
+		}
+		levelLimits[level.String()] = map[string]interface{}{
+			"per_second": limit.limitPeSecond,
+			"burst":      limit.burst,
+		}
+	}
+	sort.Strings(exempt)
+	state["exempt"] = exempt
+	state["level_limits"] = levelLimits
+
+	return state
+}
+
+// suppress counts the dropped message when the hook reports dropped messages
+func (conf *rateLimit) suppress(set *limiterSet, entry *logrus.Entry, err error) error {
+	observe(conf.metrics).RateLimitDeny(conf.stage, entryLevel(entry))
+	traceEvent(entry, "logrus.hook.ratelimit.deny",
+		Attribute{Key: "logrus_hooks.stage", Value: conf.stage},
+	)
+
+	if conf.summarize {
+		set.suppressed.add(entry, conf.now())
+	}
+
+	return err
+}
+
+// deliver sends the message to the next hook, preceded by the report of the
+// messages that were dropped before it
+func (conf *rateLimit) deliver(next logrus.Hook, set *limiterSet, entry *logrus.Entry, fields ...logrus.Fields) error {
+	observe(conf.metrics).RateLimitAllow(conf.stage, entryLevel(entry))
+	traceEvent(entry, "logrus.hook.ratelimit.allow",
+		Attribute{Key: "logrus_hooks.stage", Value: conf.stage},
+	)
+
+	if !conf.summarize {
+		return next.Fire(entry)
+	}
+
+	var summaryErr error
+	if summary := set.suppressed.summary(entry, conf.now()); summary != nil {
+		for _, f := range fields {
+			for k, v := range f {
+				summary.Data[k] = v
+			}
+		}
+		summaryErr = next.Fire(summary)
+	}
+
+	return errors.Join(summaryErr, next.Fire(entry))
+}
+
+// exempt sends the message of an exempt log level to the next hook
+func (conf *rateLimit) exempt(next logrus.Hook, entry *logrus.Entry) error {
+	observe(conf.metrics).RateLimitAllow(conf.stage, entryLevel(entry))
+
+	return next.Fire(entry)
+}
+
+// delayAt is the time until the limiter would permit one more message, it
+// looks at the tokens of the limiter without taking any of them
+func delayAt(limiter *rate.Limiter, now time.Time) time.Duration {
+	limit := float64(limiter.Limit())
+	if limit <= 0 || limiter.Burst() < 1 {
+		return 0
+	}
+
+	missing := 1 - limiter.TokensAt(now)
+	if missing <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(missing / limit * float64(time.Second)))
+}
+
+// waitFor blocks until all limiters permit one more message, the waits on
+// the limiters run at the same time so the message waits for the longest one
+//
+// The limiters fail immediately, without blocking, if the wait would run
+// past the deadline of the context or the maximum wait. On failure waitFor
+// returns the limiter that denied the message.
+func (conf *rateLimit) waitFor(entry *logrus.Entry, limiters []*rate.Limiter) (*rate.Limiter, error) {
+	ctx := entryContext(entry)
+	if err := ctx.Err(); err != nil {
+		return limiters[0], err
+	}
+
+	clock := clockOrSystem(conf.clock)
+	now := clock.Now()
+
+	var delay time.Duration
+	slowest := limiters[0]
+	reserved := make([]*rate.Reservation, 0, len(limiters))
+	for _, limiter := range limiters {
+		r := limiter.ReserveN(now, 1)
+		if !r.OK() {
+			cancelAt(reserved, now)
+			return limiter, errors.New("message exceeds the burst of the limiter")
+		}
+		reserved = append(reserved, r)
+
+		if d := r.DelayFrom(now); d > delay {
+			delay, slowest = d, limiter
+		}
+	}
+
+	if delay == 0 {
+		return nil, nil
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if (conf.maxWait > 0 && delay > conf.maxWait) || (hasDeadline && deadline.Sub(now) < delay) {
+		cancelAt(reserved, now)
+		return slowest, fmt.Errorf("wait of %s would exceed the deadline: %w", delay, context.DeadlineExceeded)
+	}
+
+	timer := clock.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil, nil
+	case <-ctx.Done():
+		cancelAt(reserved, clock.Now())
+		return slowest, ctx.Err()
+	}
+}
+
+// now is the current time of the clock of the limiters
+func (conf *rateLimit) now() time.Time {
+	return clockOrSystem(conf.clock).Now()
+}
+
+// update applies the options to a copy of the configuration, so that the
+// configuration that is in use is never modified
+func (conf *rateLimit) update(opts []RateLimitOption) rateLimit {
+	next := *conf
+	if conf.levels != nil {
+		next.levels = make(map[logrus.Level]*levelLimit, len(conf.levels))
+		for level, limit := range conf.levels {
+			next.levels[level] = limit
+		}
+	}
+
+	for _, opt := range opts {
+		opt(&next)
+	}
+
+	return next
+}
+
+// setLevel assigns a rate limit to a log level, nil makes the level exempt
+func (conf *rateLimit) setLevel(level logrus.Level, limit *levelLimit) {
+	if conf.levels == nil {
+		conf.levels = make(map[logrus.Level]*levelLimit)
+	}
+	conf.levels[level] = limit
+}
+
+// newLimiterSet creates the rate limiters for the configured limits
+func newLimiterSet(conf *rateLimit) *limiterSet {
+	set := &limiterSet{
+		defaultLimiter: rate.NewLimiter(
+			rate.Limit(conf.limitPeSecond),
+			conf.burst,
+		),
+		levels:     make(map[logrus.Level]*rate.Limiter, len(conf.levels)),
+		suppressed: &suppression{},
+	}
+
+	// levels that share a limit share the limiter too
+	groups := make(map[*levelLimit]*rate.Limiter)
+	for level, limit := range conf.levels {
+		if limit == nil {
+			set.levels[level] = nil
+			continue
+		}
+
+		limiter, found := groups[limit]
+		if !found {
+			limiter = rate.NewLimiter(rate.Limit(limit.limitPeSecond), limit.burst)
+			groups[limit] = limiter
+		}
+		set.levels[level] = limiter
+	}
+
+	return set
+}
+
+// reconfigure creates the rate limiters for a new configuration, the default
+// limiter and the count of dropped messages are carried over
+func (set *limiterSet) reconfigure(conf *rateLimit) *limiterSet {
+	next := newLimiterSet(conf)
+
+	now := conf.now()
+	set.defaultLimiter.SetLimitAt(now, rate.Limit(conf.limitPeSecond))
+	set.defaultLimiter.SetBurstAt(now, conf.burst)
+	next.defaultLimiter = set.defaultLimiter
+	next.suppressed = set.suppressed
+
+	return next
+}
+
+// forEntry selects the limiter for the log entry, nil means no limit
+func (set *limiterSet) forEntry(entry *logrus.Entry) *rate.Limiter {
+	if entry == nil {
+		return set.defaultLimiter
+	}
+
+	if limiter, found := set.levels[entry.Level]; found {
+		return limiter
 	}
 
-	return h.next.Fire(entry)
+	return set.defaultLimiter
 }
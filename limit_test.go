@@ -1,64 +1,141 @@
 package hooks
 
 import (
+	"context"
 	"fmt"
 	"testing"
-	"time"
 
-	"golang.org/x/time/rate"
+	"github.com/sirupsen/logrus"
 )
 
-func TestRateLimit(t *testing.T) {
-	testData := []rate.Limit{
+func TestBurst(t *testing.T) {
+	testData := []int{
 		1, 10, 25, 50, 100,
 	}
 
 	for _, td := range testData {
-		ratePerSecond := int(td)
 		hook := RateLimitHook(
 			&mockCannedHook{},
-			PerSecond(ratePerSecond),
+			PerSecond(1),
+			Burst(td),
 		)
 
-		t.Run(fmt.Sprintf("%d-per-sec", ratePerSecond), func(t *testing.T) {
-			for i := 0; i < ratePerSecond; i++ {
+		t.Run(fmt.Sprintf("burst of %d", td), func(t *testing.T) {
+			for i := 0; i < td; i++ {
 				if err := hook.Fire(nil); err != nil {
 					t.Fatalf("rate limited too early after %d times: %s", i, err)
 				}
-				if i < (ratePerSecond - 1) {
-					time.Sleep(time.Duration(time.Second.Nanoseconds() / int64(ratePerSecond)))
-				}
 			}
 
 			if hook.Fire(nil) == nil {
-				t.Fatalf("hook was not limited after %d times", ratePerSecond)
+				t.Fatalf("hook was not limited after %d times", td)
 			}
 		})
 	}
 }
 
-func TestBurst(t *testing.T) {
-	testData := []int{
-		1, 10, 25, 50, 100,
+func TestRateLimit_WaitContext(t *testing.T) {
+	hook := RateLimitHook(
+		&mockCannedHook{},
+		PerSecond(1),
+		Wait(0),
+	)
+
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("first message was rate limited: %s", err)
 	}
 
-	for _, td := range testData {
-		hook := RateLimitHook(
-			&mockCannedHook{},
-			PerSecond(1),
-			Burst(td),
-		)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
 
-		t.Run(fmt.Sprintf("burst of %d", td), func(t *testing.T) {
-			for i := 0; i < td; i++ {
-				if err := hook.Fire(nil); err != nil {
-					t.Fatalf("rate limited too early after %d times: %s", i, err)
-				}
-			}
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+	if hook.Fire(entry) == nil {
+		t.Fatalf("hook did not give up when the context of the entry was canceled")
+	}
+}
 
-			if hook.Fire(nil) == nil {
-				t.Fatalf("hook was not limited after %d times", td)
+func TestRateLimit_Levels(t *testing.T) {
+	hook := RateLimitHook(
+		&mockCannedHook{},
+		PerSecond(1),
+		Burst(1),
+		LevelLimit(1, 5, logrus.WarnLevel, logrus.ErrorLevel),
+		Exempt(logrus.PanicLevel, logrus.FatalLevel),
+	)
+
+	newEntry := func(level logrus.Level) *logrus.Entry {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Level = level
+		return entry
+	}
+
+	// debug messages use up the default limit
+	if err := hook.Fire(newEntry(logrus.DebugLevel)); err != nil {
+		t.Fatalf("first debug message was rate limited: %s", err)
+	}
+	if hook.Fire(newEntry(logrus.InfoLevel)) == nil {
+		t.Errorf("info message was not limited after the default burst was used up")
+	}
+
+	// warnings and errors share the burst of their group
+	for i, level := range []logrus.Level{
+		logrus.WarnLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.ErrorLevel, logrus.ErrorLevel,
+	} {
+		if err := hook.Fire(newEntry(level)); err != nil {
+			t.Errorf("%s message was rate limited too early at round [%d]: %s", level, i, err)
+		}
+	}
+	if hook.Fire(newEntry(logrus.ErrorLevel)) == nil {
+		t.Errorf("error message was not limited after the group burst was used up")
+	}
+
+	// exempt levels are never limited
+	for i := 0; i < 100; i++ {
+		if err := hook.Fire(newEntry(logrus.FatalLevel)); err != nil {
+			t.Fatalf("exempt message was rate limited at round [%d]: %s", i, err)
+		}
+	}
+}
+
+func TestRateLimit_Flush(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := RateLimitHook(
+		&mockHook,
+		PerSecond(1),
+		Burst(1),
+		Summarize(),
+	)
+
+	for i := 0; i < 3; i++ {
+		entry := newTestEntry(logrus.InfoLevel, fmt.Sprintf("test message: %d", i), nil)
+		_ = hook.Fire(entry)
+	}
+
+	flusher, ok := hook.(Flusher)
+	if !ok {
+		t.Fatalf("rate limit hook is not a flusher")
+	}
+	if err := flusher.Flush(); err != nil {
+		t.Fatalf("failed to flush the rate limit hook: %s", err)
+	}
+
+	summaries := 0
+	mockHook.messages.Range(func(key, value interface{}) bool {
+		if n := value.(*logrus.Entry).Data["suppressed"]; n != nil {
+			summaries++
+			if n != 2 {
+				t.Errorf("wrong number of suppressed messages: expected=2, found=%v", n)
 			}
-		})
+		}
+		return true
+	})
+	if summaries != 1 {
+		t.Fatalf("unexpected number of summaries: %d", summaries)
+	}
+
+	// the second flush has nothing to report
+	mockHook.reset(t)
+	if err := flusher.Flush(); err != nil || mockHook.len(t) != 0 {
+		t.Errorf("second flush sent %d messages: %v", mockHook.len(t), err)
 	}
 }
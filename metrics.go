@@ -0,0 +1,238 @@
+package hooks
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Metrics receives the events of the hooks
+//
+// Every event carries the name of the stage, so that one implementation can
+// serve several hooks. The methods are called concurrently and must not block.
+type Metrics interface {
+
+	// RetryAttempt is called before every attempt to deliver a message
+	RetryAttempt(stage string, level logrus.Level)
+
+	// RetrySuccess is called when a message was delivered after some attempts
+	RetrySuccess(stage string, level logrus.Level, attempts int)
+
+	// RetryFailure is called when a message was not delivered after all attempts
+	RetryFailure(stage string, level logrus.Level, attempts int)
+
+	// RetryBackoff is called with the pause before the next attempt
+	RetryBackoff(stage string, level logrus.Level, delay time.Duration)
+
+	// RateLimitAllow is called when a message is permitted by the rate limit
+	RateLimitAllow(stage string, level logrus.Level)
+
+	// RateLimitDeny is called when a message is dropped by the rate limit
+	RateLimitDeny(stage string, level logrus.Level)
+
+	// AsyncEnqueue is called when a message is queued up for sending
+	AsyncEnqueue(stage string, level logrus.Level)
+
+	// AsyncOverflow is called when a message is dropped because the buffer is full
+	AsyncOverflow(stage string, level logrus.Level)
+
+	// AsyncQueueDepth is called with the number of queued messages when it changes
+	AsyncQueueDepth(stage string, depth int)
+
+	// AsyncBoosters is called with the number of extra senders when it changes
+	AsyncBoosters(stage string, boosters int)
+
+	// AsyncSendLatency is called with the time it took to send out a message
+	AsyncSendLatency(stage string, level logrus.Level, latency time.Duration)
+}
+
+// NoLevel is the level of the events of messages that have no level, like
+// a nil entry, it is not one of the logrus levels
+const NoLevel = logrus.Level(math.MaxUint32)
+
+// observe replaces missing metrics with the no-op implementation
+func observe(m Metrics) Metrics {
+	if m == nil {
+		return NopMetrics{}
+	}
+
+	return m
+}
+
+// entryLevel is the level of a log entry that may be missing
+func entryLevel(entry *logrus.Entry) logrus.Level {
+	if entry == nil {
+		return NoLevel
+	}
+
+	return entry.Level
+}
+
+// NopMetrics ignores all events, it is the default metrics of the hooks
+type NopMetrics struct{}
+
+func (NopMetrics) RetryAttempt(string, logrus.Level)                    {}
+func (NopMetrics) RetrySuccess(string, logrus.Level, int)               {}
+func (NopMetrics) RetryFailure(string, logrus.Level, int)               {}
+func (NopMetrics) RetryBackoff(string, logrus.Level, time.Duration)     {}
+func (NopMetrics) RateLimitAllow(string, logrus.Level)                  {}
+func (NopMetrics) RateLimitDeny(string, logrus.Level)                   {}
+func (NopMetrics) AsyncEnqueue(string, logrus.Level)                    {}
+func (NopMetrics) AsyncOverflow(string, logrus.Level)                   {}
+func (NopMetrics) AsyncQueueDepth(string, int)                          {}
+func (NopMetrics) AsyncBoosters(string, int)                            {}
+func (NopMetrics) AsyncSendLatency(string, logrus.Level, time.Duration) {}
+
+// names of the counters, gauges and durations of MemoryMetrics
+const (
+	MetricRetryAttempts   = "retry_attempts"
+	MetricRetrySuccesses  = "retry_successes"
+	MetricRetryFailures   = "retry_failures"
+	MetricRetryBackoff    = "retry_backoff"
+	MetricRateLimitAllows = "ratelimit_allows"
+	MetricRateLimitDenies = "ratelimit_denies"
+	MetricAsyncEnqueues   = "async_enqueues"
+	MetricAsyncOverflows  = "async_overflows"
+	MetricAsyncQueueDepth = "async_queue_depth"
+	MetricAsyncBoosters   = "async_boosters"
+	MetricAsyncLatency    = "async_send_latency"
+)
+
+// MemoryMetrics keeps the events of the hooks in memory, it is meant for tests
+type MemoryMetrics struct {
+	sync.Mutex
+
+	counters  map[metricKey]int64
+	durations map[metricKey]time.Duration
+	gauges    map[metricKey]int64
+}
+
+// metricKey identifies a value of MemoryMetrics
+type metricKey struct {
+	name  string
+	stage string
+	level logrus.Level
+}
+
+// NewMemoryMetrics creates an empty set of metrics
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		counters:  make(map[metricKey]int64),
+		durations: make(map[metricKey]time.Duration),
+		gauges:    make(map[metricKey]int64),
+	}
+}
+
+// Counter is the number of events of a stage, for all log levels
+func (m *MemoryMetrics) Counter(name, stage string) int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	var total int64
+	for key, n := range m.counters {
+		if key.name == name && key.stage == stage {
+			total += n
+		}
+	}
+
+	return total
+}
+
+// LevelCounter is the number of events of a stage for one log level
+func (m *MemoryMetrics) LevelCounter(name, stage string, level logrus.Level) int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.counters[metricKey{name, stage, level}]
+}
+
+// Duration is the total time reported by the events of a stage
+func (m *MemoryMetrics) Duration(name, stage string) time.Duration {
+	m.Lock()
+	defer m.Unlock()
+
+	var total time.Duration
+	for key, d := range m.durations {
+		if key.name == name && key.stage == stage {
+			total += d
+		}
+	}
+
+	return total
+}
+
+// Gauge is the last value reported by the events of a stage
+func (m *MemoryMetrics) Gauge(name, stage string) int64 {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.gauges[metricKey{name: name, stage: stage}]
+}
+
+func (m *MemoryMetrics) count(name, stage string, level logrus.Level) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.counters[metricKey{name, stage, level}]++
+}
+
+func (m *MemoryMetrics) add(name, stage string, level logrus.Level, d time.Duration) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.counters[metricKey{name, stage, level}]++
+	m.durations[metricKey{name, stage, level}] += d
+}
+
+func (m *MemoryMetrics) set(name, stage string, value int64) {
+	m.Lock()
+	defer m.Unlock()
+
+	m.gauges[metricKey{name: name, stage: stage}] = value
+}
+
+func (m *MemoryMetrics) RetryAttempt(stage string, level logrus.Level) {
+	m.count(MetricRetryAttempts, stage, level)
+}
+
+func (m *MemoryMetrics) RetrySuccess(stage string, level logrus.Level, _ int) {
+	m.count(MetricRetrySuccesses, stage, level)
+}
+
+func (m *MemoryMetrics) RetryFailure(stage string, level logrus.Level, _ int) {
+	m.count(MetricRetryFailures, stage, level)
+}
+
+func (m *MemoryMetrics) RetryBackoff(stage string, level logrus.Level, delay time.Duration) {
+	m.add(MetricRetryBackoff, stage, level, delay)
+}
+
+func (m *MemoryMetrics) RateLimitAllow(stage string, level logrus.Level) {
+	m.count(MetricRateLimitAllows, stage, level)
+}
+
+func (m *MemoryMetrics) RateLimitDeny(stage string, level logrus.Level) {
+	m.count(MetricRateLimitDenies, stage, level)
+}
+
+func (m *MemoryMetrics) AsyncEnqueue(stage string, level logrus.Level) {
+	m.count(MetricAsyncEnqueues, stage, level)
+}
+
+func (m *MemoryMetrics) AsyncOverflow(stage string, level logrus.Level) {
+	m.count(MetricAsyncOverflows, stage, level)
+}
+
+func (m *MemoryMetrics) AsyncQueueDepth(stage string, depth int) {
+	m.set(MetricAsyncQueueDepth, stage, int64(depth))
+}
+
+func (m *MemoryMetrics) AsyncBoosters(stage string, boosters int) {
+	m.set(MetricAsyncBoosters, stage, int64(boosters))
+}
+
+func (m *MemoryMetrics) AsyncSendLatency(stage string, level logrus.Level, latency time.Duration) {
+	m.add(MetricAsyncLatency, stage, level, latency)
+}
@@ -0,0 +1,179 @@
+package hooks
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMetrics_Retry(t *testing.T) {
+	metrics := NewMemoryMetrics()
+
+	hook := RetryHook(
+		&mockRetryHook{maxFailures: 2},
+		time.Microsecond,
+		Retries(3),
+		RetryMetrics(metrics, "retry"),
+	)
+	if err := hook.Fire(nil); err != nil {
+		t.Fatalf("failed with 3 retries: %s", err)
+	}
+
+	hook = RetryHook(
+		&mockRetryHook{maxFailures: 5},
+		time.Microsecond,
+		Retries(1),
+		RetryMetrics(metrics, "retry"),
+	)
+	if err := hook.Fire(nil); err == nil {
+		t.Fatalf("success with 1 retry")
+	}
+
+	testData := []struct {
+		name     string
+		expected int64
+	}{
+		{MetricRetryAttempts, 5},
+		{MetricRetrySuccesses, 1},
+		{MetricRetryFailures, 1},
+		{MetricRetryBackoff, 3},
+	}
+
+	for _, td := range testData {
+		if n := metrics.Counter(td.name, "retry"); n != td.expected {
+			t.Errorf("wrong value of %s: expected=%d, found=%d", td.name, td.expected, n)
+		}
+	}
+	if d := metrics.Duration(MetricRetryBackoff, "retry"); d <= 0 {
+		t.Errorf("backoff time was not reported")
+	}
+
+	// the messages without a level are not counted as panics
+	if n := metrics.LevelCounter(MetricRetryAttempts, "retry", NoLevel); n != 5 {
+		t.Errorf("wrong number of attempts without a level: expected=5, found=%d", n)
+	}
+	if n := metrics.LevelCounter(MetricRetryAttempts, "retry", logrus.PanicLevel); n != 0 {
+		t.Errorf("attempts without a level were counted as panics: %d", n)
+	}
+}
+
+func TestMetrics_RateLimit(t *testing.T) {
+	metrics := NewMemoryMetrics()
+
+	hook := RateLimitHook(
+		&mockCannedHook{},
+		PerSecond(1),
+		Burst(3),
+		Exempt(logrus.ErrorLevel),
+		RateLimitMetrics(metrics, "limits"),
+	)
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Level = logrus.InfoLevel
+	for i := 0; i < 10; i++ {
+		_ = hook.Fire(entry)
+	}
+
+	entry.Level = logrus.ErrorLevel
+	_ = hook.Fire(entry)
+
+	if n := metrics.Counter(MetricRateLimitAllows, "limits"); n != 4 {
+		t.Errorf("wrong number of allowed messages: expected=4, found=%d", n)
+	}
+	if n := metrics.LevelCounter(MetricRateLimitAllows, "limits", logrus.ErrorLevel); n != 1 {
+		t.Errorf("wrong number of allowed errors: expected=1, found=%d", n)
+	}
+	if n := metrics.LevelCounter(MetricRateLimitDenies, "limits", logrus.InfoLevel); n != 7 {
+		t.Errorf("wrong number of denied messages: expected=7, found=%d", n)
+	}
+}
+
+func TestMetrics_Async(t *testing.T) {
+	metrics := NewMemoryMetrics()
+
+	var mockHook mockRecordingHook
+	hook := AsyncHook(&mockHook, Senders(0), BoostSenders(0), BufferLen(4), AsyncMetrics(metrics, "async"))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		testMessage := logrus.NewEntry(logrus.StandardLogger())
+		testMessage.Message = fmt.Sprintf("test message: %d", i)
+		_ = hook.Fire(testMessage)
+	}
+
+	if n := metrics.Counter(MetricAsyncEnqueues, "async"); n != 4 {
+		t.Errorf("wrong number of queued messages: expected=4, found=%d", n)
+	}
+	if n := metrics.Counter(MetricAsyncOverflows, "async"); n != 2 {
+		t.Errorf("wrong number of overflows: expected=2, found=%d", n)
+	}
+	if n := metrics.Gauge(MetricAsyncQueueDepth, "async"); n != 4 {
+		t.Errorf("wrong queue depth: expected=4, found=%d", n)
+	}
+
+	// the senders empty out the queue
+	if err := hook.(Reconfigurable[AsyncOption]).Reconfigure(Senders(2), BoostSenders(2)); err != nil {
+		t.Fatalf("failed to reconfigure the async hook: %s", err)
+	}
+	if n := metrics.Gauge(MetricAsyncQueueDepth, "async"); n != 0 {
+		t.Errorf("queue depth was not reset by the restart of the senders: %d", n)
+	}
+	for i := 0; i < 4; i++ {
+		testMessage := logrus.NewEntry(logrus.StandardLogger())
+		testMessage.Message = fmt.Sprintf("another test message: %d", i)
+		if err := hook.Fire(testMessage); err != nil {
+			t.Fatalf("fire failed at round [%d]: %s", i, err)
+		}
+	}
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	if n := metrics.Counter(MetricAsyncLatency, "async"); n != 4 {
+		t.Errorf("wrong number of sent messages: expected=4, found=%d", n)
+	}
+	if n := metrics.Gauge(MetricAsyncQueueDepth, "async"); n != 0 {
+		t.Errorf("wrong queue depth: expected=0, found=%d", n)
+	}
+	if n := metrics.Gauge(MetricAsyncBoosters, "async"); n != 0 {
+		t.Errorf("wrong number of boosters: expected=0, found=%d", n)
+	}
+}
+
+func TestMetrics_Load(t *testing.T) {
+	metrics := NewMemoryMetrics()
+
+	registry := NewRegistry()
+	registry.Register("sink", &mockCannedHook{})
+	registry.SetMetrics(metrics)
+
+	pipeline, err := Load([]byte(`
+sink: sink
+stages:
+  - type: rate_limit
+    name: audit-limits
+    per_second: 100
+  - type: retry
+    name: audit-retry
+    delay: 1ms
+`), registry)
+	if err != nil {
+		t.Fatalf("failed to load the pipeline: %s", err)
+	}
+
+	if err := pipeline.Fire(logrus.NewEntry(logrus.StandardLogger())); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if n := metrics.Counter(MetricRateLimitAllows, "audit-limits"); n != 1 {
+		t.Errorf("wrong number of allowed messages: expected=1, found=%d", n)
+	}
+	if n := metrics.Counter(MetricRetryAttempts, "audit-retry"); n != 1 {
+		t.Errorf("wrong number of attempts: expected=1, found=%d", n)
+	}
+}
@@ -21,12 +21,73 @@ func (mock *mockCannedHook) Fire(entry *logrus.Entry) error {
 	return mock.fireResult
 }
 
+// mockLastHook is a hook that keeps the last message it has received
+type mockLastHook struct {
+	ChainImpl
+	entry *logrus.Entry
+}
+
+func (mock *mockLastHook) Fire(entry *logrus.Entry) error {
+	mock.entry = entry
+	return nil
+}
+
+// mockOrderedHook is a hook that keeps the messages in the order they were received
+type mockOrderedHook struct {
+	ChainImpl
+	sync.Mutex
+	messages []string
+	entries  []*logrus.Entry
+}
+
+func (mock *mockOrderedHook) Fire(entry *logrus.Entry) error {
+	mock.Lock()
+	defer mock.Unlock()
+
+	mock.messages = append(mock.messages, entry.Message)
+	mock.entries = append(mock.entries, entry)
+	return nil
+}
+
+// mockRunningHook is a hook that can be started and stopped, it fails to
+// start with the canned error
+type mockRunningHook struct {
+	mockCannedHook
+	startResult error
+	running     bool
+	starts      int
+}
+
+func (mock *mockRunningHook) IsRunning() bool {
+	return mock.running
+}
+
+func (mock *mockRunningHook) Start() error {
+	mock.starts++
+	if mock.startResult != nil {
+		return mock.startResult
+	}
+
+	mock.running = true
+	return nil
+}
+
+func (mock *mockRunningHook) Stop() error {
+	mock.running = false
+	return nil
+}
+
 // mockRecordingHook is hook that keeps all messages it has received
 type mockRecordingHook struct {
 	ChainImpl
 	messages sync.Map
 }
 
+// Levels are all logging levels, the mock is the last hook of the chain
+func (mock *mockRecordingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
 // Fire stores a copy of the message
 func (mock *mockRecordingHook) Fire(entry *logrus.Entry) error {
 	entry2 := *entry
@@ -84,3 +145,12 @@ func (mock *mockRecordingHook) compare(t *testing.T, sent []*logrus.Entry) {
 			len(sent), nReceived)
 	}
 }
+
+// newTestEntry creates a log entry with the level, message and fields
+func newTestEntry(level logrus.Level, msg string, fields logrus.Fields) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(fields)
+	entry.Level = level
+	entry.Message = msg
+
+	return entry
+}
@@ -0,0 +1,197 @@
+// Package otelhooks traces the delivery of the messages of the Logrus hooks
+// with OpenTelemetry
+//
+// The TraceHook starts a span around the delivery of a message and passes it
+// to the next hooks as their hooks.Span. The retry, rate limit and async
+// hooks record their work as events of the span, and the async hook adds a
+// child span that covers the time a message waits in the queue.
+package otelhooks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	hooks "logrus-hooks.git"
+)
+
+const (
+	// tracerName is the name of the tracer of the hooks
+	tracerName = "logrus-hooks"
+
+	// DefaultSpanName is the name of the span around the delivery of a message
+	DefaultSpanName = "logrus.hook.deliver"
+)
+
+// traceHook is a Logrus hook that traces the delivery of the messages
+type traceHook struct {
+	next logrus.Hook
+	conf traceParams
+}
+
+// traceParams defines the spans created by the hook
+type traceParams struct {
+	provider trace.TracerProvider
+	spanName string
+}
+
+// span is the hooks.Span of an OpenTelemetry span
+type span struct {
+	span trace.Span
+}
+
+// constructor --------------------------------------------------------
+
+// TraceOption is a functional option to update the trace hook configuration
+type TraceOption func(conf *traceParams)
+
+// TracerProvider sets the provider of the tracer that creates the spans,
+// the global provider is used by default
+func TracerProvider(tp trace.TracerProvider) TraceOption {
+	return func(conf *traceParams) {
+		conf.provider = tp
+	}
+}
+
+// SpanName sets the name of the span around the delivery of a message
+func SpanName(name string) TraceOption {
+	return func(conf *traceParams) {
+		conf.spanName = name
+	}
+}
+
+// TraceHook creates a Logrus hook that traces the delivery of the messages
+//
+// The span is a child of the span in the context of the log entry and it is
+// passed to the next hooks in the context of a copy of the entry.
+func TraceHook(next logrus.Hook, opts ...TraceOption) logrus.Hook {
+
+	hook := &traceHook{
+		next: next,
+		// default configuration
+		conf: traceParams{
+			spanName: DefaultSpanName,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire delivers the message to the next hook within a span
+func (h *traceHook) Fire(entry *logrus.Entry) error {
+	provider := h.conf.provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	traced := copyEntry(entry)
+	ctx := context.Background()
+	if traced.Context != nil {
+		ctx = traced.Context
+	}
+
+	ctx, s := provider.Tracer(tracerName).Start(ctx, h.conf.spanName,
+		trace.WithAttributes(
+			attribute.String("log.level", traced.Level.String()),
+		),
+	)
+	defer s.End()
+
+	traced.Context = ctx
+	if s.IsRecording() {
+		traced.Context = hooks.ContextWithSpan(ctx, &span{span: s})
+	}
+
+	err := h.next.Fire(traced)
+	if err != nil {
+		s.RecordError(err)
+		s.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
+
+// Levels are the log levels of the next hook
+func (h *traceHook) Levels() []logrus.Level {
+	return h.next.Levels()
+}
+
+// Next is the hook that receives the traced messages
+func (h *traceHook) Next() logrus.Hook {
+	return h.next
+}
+
+// AddEvent adds an event to the span
+func (s *span) AddEvent(name string, attrs ...hooks.Attribute) {
+	s.span.AddEvent(name, trace.WithAttributes(attributes(attrs)...))
+}
+
+// Start starts a child span with the tracer of the span
+func (s *span) Start(ctx context.Context, name string, attrs ...hooks.Attribute) (context.Context, hooks.Span) {
+	ctx, child := s.span.TracerProvider().Tracer(tracerName).Start(ctx, name,
+		trace.WithAttributes(attributes(attrs)...),
+	)
+
+	childSpan := &span{span: child}
+
+	return hooks.ContextWithSpan(ctx, childSpan), childSpan
+}
+
+// End ends the span, the error is recorded in the span
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+	s.span.End()
+}
+
+// attributes converts the attributes of the hooks to OpenTelemetry attributes
+func attributes(attrs []hooks.Attribute) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		switch value := attr.Value.(type) {
+		case string:
+			kvs = append(kvs, attribute.String(attr.Key, value))
+		case int:
+			kvs = append(kvs, attribute.Int(attr.Key, value))
+		case int64:
+			kvs = append(kvs, attribute.Int64(attr.Key, value))
+		case float64:
+			kvs = append(kvs, attribute.Float64(attr.Key, value))
+		case bool:
+			kvs = append(kvs, attribute.Bool(attr.Key, value))
+		default:
+			kvs = append(kvs, attribute.String(attr.Key, fmt.Sprint(value)))
+		}
+	}
+
+	return kvs
+}
+
+// copyEntry makes a copy of a log entry that is not affected by changes to
+// the fields of the original entry
+func copyEntry(entry *logrus.Entry) *logrus.Entry {
+	if entry == nil {
+		return logrus.NewEntry(logrus.StandardLogger())
+	}
+
+	dup := *entry
+	dup.Data = make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		dup.Data[k] = v
+	}
+
+	return &dup
+}
@@ -0,0 +1,163 @@
+package otelhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	hooks "logrus-hooks.git"
+	"logrus-hooks.git/hookstest"
+)
+
+// newTestTracing creates a tracer provider that keeps the spans in memory
+func newTestTracing(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return provider, exporter
+}
+
+// countEvents counts the events of a span with the given name
+func countEvents(span tracetest.SpanStub, name string) int {
+	n := 0
+	for _, event := range span.Events {
+		if event.Name == name {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestTrace_Retry(t *testing.T) {
+	provider, exporter := newTestTracing(t)
+
+	clock := hookstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	clock.SetAutoAdvance(true)
+
+	hook := TraceHook(
+		hooks.RetryHook(hookstest.NewScriptedHook(nil, hookstest.FailTimes(2, nil)...), time.Microsecond,
+			hooks.Retries(3),
+			hooks.RetryClock(clock),
+		),
+		TracerProvider(provider),
+	)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("failed with 3 retries: %s", err)
+	}
+	parent.End()
+
+	if entry.Context != ctx {
+		t.Errorf("context of the original entry was changed")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("wrong number of spans: expected=2, found=%d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name != DefaultSpanName {
+		t.Errorf("wrong name of the span: %s", span.Name)
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("span is not a child of the span of the entry")
+	}
+	if n := countEvents(span, "logrus.hook.retry.failure"); n != 2 {
+		t.Errorf("wrong number of failure events: expected=2, found=%d", n)
+	}
+	if n := countEvents(span, "logrus.hook.retry.backoff"); n != 2 {
+		t.Errorf("wrong number of backoff events: expected=2, found=%d", n)
+	}
+}
+
+func TestTrace_RateLimit(t *testing.T) {
+	provider, exporter := newTestTracing(t)
+
+	hook := TraceHook(
+		hooks.RateLimitHook(hookstest.NewRecorder(), hooks.PerSecond(1), hooks.Burst(1)),
+		TracerProvider(provider),
+		SpanName("deliver"),
+	)
+
+	for i := 0; i < 2; i++ {
+		_ = hook.Fire(logrus.NewEntry(logrus.StandardLogger()))
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("wrong number of spans: expected=2, found=%d", len(spans))
+	}
+	if spans[0].Name != "deliver" {
+		t.Errorf("wrong name of the span: %s", spans[0].Name)
+	}
+	if n := countEvents(spans[0], "logrus.hook.ratelimit.allow"); n != 1 {
+		t.Errorf("permitted message has no allow event")
+	}
+	if n := countEvents(spans[1], "logrus.hook.ratelimit.deny"); n != 1 {
+		t.Errorf("dropped message has no deny event")
+	}
+	if spans[1].Status.Code != codes.Error {
+		t.Errorf("span of the dropped message is not an error: %v", spans[1].Status)
+	}
+}
+
+func TestTrace_Async(t *testing.T) {
+	provider, exporter := newTestTracing(t)
+
+	sink := hookstest.NewRecorder()
+	async := hooks.AsyncHook(sink, hooks.AsyncMetrics(nil, "async"))
+	hook := TraceHook(async, TracerProvider(provider))
+
+	if err := async.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "test message"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if err := async.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("wrong number of spans: expected=2, found=%d", len(spans))
+	}
+
+	var deliver, queued tracetest.SpanStub
+	for _, span := range spans {
+		switch span.Name {
+		case DefaultSpanName:
+			deliver = span
+		case "logrus.hook.async":
+			queued = span
+		}
+	}
+
+	if queued.Parent.SpanID() != deliver.SpanContext.SpanID() {
+		t.Errorf("span of the async hook is not a child of the delivery span")
+	}
+	if n := countEvents(queued, "logrus.hook.async.dequeue"); n != 1 {
+		t.Errorf("span of the async hook has no dequeue event")
+	}
+	if len(queued.Attributes) != 1 || queued.Attributes[0].Value.AsString() != "async" {
+		t.Errorf("wrong attributes of the span of the async hook: %v", queued.Attributes)
+	}
+
+	sink.AssertMessages(t, "test message")
+}
@@ -0,0 +1,30 @@
+package otelhooks
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	hooks "logrus-hooks.git"
+)
+
+// SpanContext makes the trace fields hook add the trace_id, span_id and
+// trace_flags of the OpenTelemetry span in the context of the message
+func SpanContext() hooks.TraceFieldsOption {
+	return hooks.Extractor(spanContextFields)
+}
+
+// spanContextFields reads the identifiers of the OpenTelemetry span in the context
+func spanContextFields(ctx context.Context) logrus.Fields {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+
+	return logrus.Fields{
+		"trace_id":    sc.TraceID().String(),
+		"span_id":     sc.SpanID().String(),
+		"trace_flags": sc.TraceFlags().String(),
+	}
+}
@@ -0,0 +1,41 @@
+package otelhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+
+	hooks "logrus-hooks.git"
+	"logrus-hooks.git/hookstest"
+)
+
+func TestSpanContext(t *testing.T) {
+	sink := hookstest.NewRecorder()
+	hook := hooks.TraceFieldsHook(sink, SpanContext())
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	entries := []*logrus.Entry{
+		logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx),
+		logrus.NewEntry(logrus.StandardLogger()).WithContext(context.Background()),
+	}
+	for _, entry := range entries {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	sink.AssertField(t, 0, "trace_id", "4bf92f3577b34da6a3ce929d0e0e4736")
+	sink.AssertField(t, 0, "span_id", "00f067aa0ba902b7")
+	sink.AssertField(t, 0, "trace_flags", "01")
+
+	// a message without a span has no trace fields
+	sink.AssertNoField(t, 1, "trace_id")
+}
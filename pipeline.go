@@ -0,0 +1,259 @@
+package hooks
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxRecentErrors is the number of errors kept by a pipeline
+const maxRecentErrors = 16
+
+// Pipeline is a chain of hooks that is managed as a whole
+//
+// The first stage of the pipeline receives the messages from the logger and
+// the last stage is the sink that sends them out. The stages that can be
+// started and stopped are started and stopped together with the pipeline.
+type Pipeline struct {
+	sync.Mutex
+
+	name    string
+	stages  []Stage
+	running bool
+	paused  atomic.Bool
+
+	// dropped is the number of messages dropped while the pipeline was paused
+	dropped atomic.Uint64
+
+	// recentErrors holds the last errors returned by the stages
+	errorsLock   sync.Mutex
+	recentErrors []ErrorRecord
+}
+
+// Stage is one hook in the chain of a pipeline
+type Stage struct {
+	// Name identifies the stage in the pipeline
+	Name string
+
+	// Type is the kind of hook, like "retry" or "async"
+	Type string
+
+	// Hook is the hook of the stage
+	Hook logrus.Hook
+}
+
+// ErrorRecord is an error returned by the stages of a pipeline
+type ErrorRecord struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// PipelineState describes the configuration and the state of a pipeline
+type PipelineState struct {
+	Name         string        `json:"name"`
+	Running      bool          `json:"running"`
+	Paused       bool          `json:"paused"`
+	Dropped      uint64        `json:"dropped"`
+	Stages       []StageState  `json:"stages"`
+	RecentErrors []ErrorRecord `json:"recent_errors"`
+}
+
+// StageState describes the configuration and the state of a stage
+type StageState struct {
+	Name  string                 `json:"name"`
+	Type  string                 `json:"type"`
+	State map[string]interface{} `json:"state,omitempty"`
+}
+
+// NewPipeline creates a pipeline out of the stages of a chain of hooks, the
+// stages are listed in the order in which they receive the messages
+func NewPipeline(name string, stages ...Stage) *Pipeline {
+	return &Pipeline{
+		name:   name,
+		stages: stages,
+	}
+}
+
+// CaptureAsyncErrors makes the async stages pass the errors of the messages
+// they send out in the background to the pipeline, which keeps them among its
+// recent errors like the errors of Fire
+//
+// The error handler is added to the hooks of the stages, so it stays with
+// them if they are used in other chains too.
+func (p *Pipeline) CaptureAsyncErrors() *Pipeline {
+	for _, stage := range p.stages {
+		if hook, ok := stage.Hook.(Reconfigurable[AsyncOption]); ok {
+			_ = hook.Reconfigure(AsyncErrorHandler(p.recordError))
+		}
+	}
+
+	return p
+}
+
+// Name is the name of the pipeline
+func (p *Pipeline) Name() string {
+	return p.name
+}
+
+// Stages lists the stages of the pipeline, from the first to the last
+func (p *Pipeline) Stages() []Stage {
+	return append([]Stage(nil), p.stages...)
+}
+
+// Fire sends the message to the first stage of the pipeline
+func (p *Pipeline) Fire(entry *logrus.Entry) error {
+	if len(p.stages) == 0 {
+		return nil
+	}
+	if p.paused.Load() {
+		p.dropped.Add(1)
+		return nil
+	}
+
+	err := p.stages[0].Hook.Fire(entry)
+	if err != nil {
+		p.recordError(err)
+	}
+
+	return err
+}
+
+// Levels are the logging levels of the first stage of the pipeline
+func (p *Pipeline) Levels() []logrus.Level {
+	if len(p.stages) == 0 {
+		return nil
+	}
+
+	return p.stages[0].Hook.Levels()
+}
+
+// IsRunning queries the state of the pipeline
+func (p *Pipeline) IsRunning() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.running
+}
+
+// Start starts the stages of the pipeline, from the last to the first, so
+// that every stage is ready before messages are sent to it
+//
+// When a stage fails to start, the stages that were started before it are
+// stopped again and the pipeline is left stopped.
+func (p *Pipeline) Start() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.running {
+		return nil
+	}
+
+	for i := len(p.stages) - 1; i >= 0; i-- {
+		if hook, ok := p.stages[i].Hook.(RunningHook); ok {
+			if err := hook.Start(); err != nil {
+				return errors.Join(err, stopStages(p.stages[i+1:]))
+			}
+		}
+	}
+
+	p.running = true
+
+	return nil
+}
+
+// Stop stops the stages of the pipeline, from the first to the last, so
+// that the messages queued up in a stage can still be sent out
+func (p *Pipeline) Stop() error {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.running {
+		return nil
+	}
+
+	err := stopStages(p.stages)
+	p.running = false
+
+	return err
+}
+
+// stopStages stops the stages, from the first to the last
+func stopStages(stages []Stage) error {
+	var errs []error
+	for _, stage := range stages {
+		if hook, ok := stage.Hook.(RunningHook); ok {
+			errs = append(errs, hook.Stop())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Pause makes the pipeline drop all messages until it is resumed, the
+// messages are dropped without errors and counted in the state
+func (p *Pipeline) Pause() {
+	p.paused.Store(true)
+}
+
+// Resume makes the paused pipeline send messages again
+func (p *Pipeline) Resume() {
+	p.paused.Store(false)
+}
+
+// Flush makes the stages send out the messages they are holding back, from
+// the first to the last stage so that nothing is left behind
+func (p *Pipeline) Flush() error {
+	var errs []error
+	for _, stage := range p.stages {
+		if hook, ok := stage.Hook.(Flusher); ok {
+			errs = append(errs, hook.Flush())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// State describes the configuration and the state of the pipeline
+func (p *Pipeline) State() PipelineState {
+	state := PipelineState{
+		Name:    p.name,
+		Running: p.IsRunning(),
+		Paused:  p.paused.Load(),
+		Dropped: p.dropped.Load(),
+		Stages:  make([]StageState, 0, len(p.stages)),
+	}
+
+	for _, stage := range p.stages {
+		stageState := StageState{
+			Name: stage.Name,
+			Type: stage.Type,
+		}
+		if hook, ok := stage.Hook.(Inspector); ok {
+			stageState.State = hook.Inspect()
+		}
+		state.Stages = append(state.Stages, stageState)
+	}
+
+	p.errorsLock.Lock()
+	state.RecentErrors = append([]ErrorRecord{}, p.recentErrors...)
+	p.errorsLock.Unlock()
+
+	return state
+}
+
+// recordError keeps the error among the recent errors of the pipeline
+func (p *Pipeline) recordError(err error) {
+	p.errorsLock.Lock()
+	defer p.errorsLock.Unlock()
+
+	if len(p.recentErrors) == maxRecentErrors {
+		p.recentErrors = append(p.recentErrors[:0], p.recentErrors[1:]...)
+	}
+	p.recentErrors = append(p.recentErrors, ErrorRecord{
+		Time:  time.Now(),
+		Error: err.Error(),
+	})
+}
@@ -0,0 +1,189 @@
+package hooks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestPipeline_StartStop(t *testing.T) {
+	var mockHook mockRecordingHook
+
+	async := AsyncHook(&mockHook)
+	pipeline := NewPipeline("test",
+		Stage{Name: "retry", Type: "retry", Hook: RetryHook(async, 0)},
+		Stage{Name: "async", Type: "async", Hook: async},
+		Stage{Name: "sink", Type: "sink", Hook: &mockHook},
+	)
+
+	if pipeline.IsRunning() || async.IsRunning() {
+		t.Fatalf("pipeline is running before it was started")
+	}
+	if err := pipeline.Fire(logrus.NewEntry(logrus.StandardLogger())); err != ErrNotRunning {
+		t.Errorf("unexpected result from Fire before the start: %v", err)
+	}
+
+	if err := pipeline.Start(); err != nil {
+		t.Fatalf("failed to start the pipeline: %s", err)
+	}
+	if !pipeline.IsRunning() || !async.IsRunning() {
+		t.Fatalf("pipeline is not running after the start")
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "test message"
+	if err := pipeline.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop the pipeline: %s", err)
+	}
+	if pipeline.IsRunning() || async.IsRunning() {
+		t.Fatalf("pipeline is running after the stop")
+	}
+
+	mockHook.compare(t, []*logrus.Entry{entry})
+}
+
+func TestPipeline_StopBeforeStart(t *testing.T) {
+	async := AsyncHook(&mockCannedHook{})
+	pipeline := NewPipeline("test", Stage{Name: "async", Type: "async", Hook: async})
+
+	if err := pipeline.Stop(); err != nil {
+		t.Errorf("stop before the start failed: %s", err)
+	}
+	if pipeline.IsRunning() || async.IsRunning() {
+		t.Errorf("pipeline is running after the stop")
+	}
+}
+
+func TestPipeline_StartTwice(t *testing.T) {
+	var mockHook mockRecordingHook
+
+	async := AsyncHook(&mockHook)
+	first := &mockRunningHook{}
+	pipeline := NewPipeline("test",
+		Stage{Name: "first", Type: "mock", Hook: first},
+		Stage{Name: "async", Type: "async", Hook: async},
+	)
+
+	for i := 0; i < 2; i++ {
+		if err := pipeline.Start(); err != nil {
+			t.Fatalf("failed to start the pipeline at round [%d]: %s", i, err)
+		}
+	}
+	if first.starts != 1 {
+		t.Errorf("stage was started %d times", first.starts)
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "test message"
+	if err := async.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+	if err := async.Start(); err != nil {
+		t.Fatalf("failed to start the running async hook: %s", err)
+	}
+
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop the pipeline: %s", err)
+	}
+	mockHook.compare(t, []*logrus.Entry{entry})
+}
+
+func TestPipeline_StartFailure(t *testing.T) {
+	startErr := errors.New("stage failed to start")
+
+	first := &mockRunningHook{startResult: startErr}
+	async := AsyncHook(&mockCannedHook{})
+	last := &mockRunningHook{}
+	pipeline := NewPipeline("test",
+		Stage{Name: "first", Type: "mock", Hook: first},
+		Stage{Name: "async", Type: "async", Hook: async},
+		Stage{Name: "last", Type: "mock", Hook: last},
+	)
+
+	if err := pipeline.Start(); !errors.Is(err, startErr) {
+		t.Fatalf("unexpected result of the failed start: %v", err)
+	}
+	if pipeline.IsRunning() || async.IsRunning() || last.IsRunning() {
+		t.Errorf("stages are running after the failed start")
+	}
+}
+
+func TestPipeline_Empty(t *testing.T) {
+	pipeline := NewPipeline("empty")
+
+	if err := pipeline.Fire(nil); err != nil {
+		t.Errorf("empty pipeline failed to fire: %s", err)
+	}
+	if levels := pipeline.Levels(); len(levels) != 0 {
+		t.Errorf("empty pipeline has levels: %v", levels)
+	}
+}
+
+func TestPipeline_State(t *testing.T) {
+	cannedHook := &mockCannedHook{fireResult: ErrBufferFull}
+	pipeline := NewPipeline("test",
+		Stage{Name: "retry", Type: "retry", Hook: RetryHook(cannedHook, 0, Retries(0))},
+		Stage{Name: "sink", Type: "sink", Hook: cannedHook},
+	)
+
+	for i := 0; i < maxRecentErrors+5; i++ {
+		if err := pipeline.Fire(nil); err == nil {
+			t.Fatalf("fire did not fail at round [%d]", i)
+		}
+	}
+
+	state := pipeline.State()
+	if len(state.RecentErrors) != maxRecentErrors {
+		t.Errorf("wrong number of recent errors: expected=%d, found=%d",
+			maxRecentErrors, len(state.RecentErrors))
+	}
+	if len(state.Stages) != 2 || state.Stages[0].State["retries"] != 0 {
+		t.Errorf("unexpected state of the stages: %+v", state.Stages)
+	}
+	if state.Stages[1].State != nil {
+		t.Errorf("sink without inspector has state: %v", state.Stages[1].State)
+	}
+
+	pipeline.Pause()
+	for i := 0; i < 3; i++ {
+		if err := pipeline.Fire(nil); err != nil {
+			t.Errorf("paused pipeline returned an error: %s", err)
+		}
+	}
+	if state := pipeline.State(); !state.Paused || state.Dropped != 3 {
+		t.Errorf("wrong state of the paused pipeline: paused=%t, dropped=%d", state.Paused, state.Dropped)
+	}
+}
+
+func TestPipeline_AsyncErrors(t *testing.T) {
+	cannedHook := &mockCannedHook{fireResult: errors.New("sink is down")}
+
+	async := AsyncHook(cannedHook)
+	pipeline := NewPipeline("test",
+		Stage{Name: "async", Type: "async", Hook: async},
+		Stage{Name: "sink", Type: "sink", Hook: cannedHook},
+	).CaptureAsyncErrors()
+
+	if err := pipeline.Start(); err != nil {
+		t.Fatalf("failed to start the pipeline: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := pipeline.Fire(nil); err != nil {
+			t.Fatalf("fire failed at round [%d]: %s", i, err)
+		}
+	}
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop the pipeline: %s", err)
+	}
+
+	// the errors of the senders are kept by the pipeline
+	state := pipeline.State()
+	if len(state.RecentErrors) != 3 || state.RecentErrors[0].Error != "sink is down" {
+		t.Errorf("wrong recent errors of the async stage: %+v", state.RecentErrors)
+	}
+}
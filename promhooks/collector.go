@@ -0,0 +1,221 @@
+// Package promhooks exports the metrics of the Logrus hooks to Prometheus
+//
+// The Collector receives the events of the hooks as their hooks.Metrics and
+// exposes them as Prometheus metrics labeled by the name of the stage and
+// the log level, the events of messages without a level are labeled "none":
+//
+//	logrus_hooks_retry_attempts_total
+//	logrus_hooks_retry_successes_total
+//	logrus_hooks_retry_failures_total
+//	logrus_hooks_retry_backoff_seconds
+//	logrus_hooks_ratelimit_allowed_total
+//	logrus_hooks_ratelimit_dropped_total
+//	logrus_hooks_async_enqueued_total
+//	logrus_hooks_async_overflow_total
+//	logrus_hooks_async_queue_depth
+//	logrus_hooks_async_boosters
+//	logrus_hooks_async_send_latency_seconds
+package promhooks
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	hooks "logrus-hooks.git"
+)
+
+const (
+	// namespace is the prefix of the names of all metrics
+	namespace = "logrus_hooks"
+
+	// labels of the metrics
+	labelStage = "stage"
+	labelLevel = "level"
+)
+
+// Collector is a Prometheus collector of the events of the hooks
+type Collector struct {
+	retryAttempts  *prometheus.CounterVec
+	retrySuccesses *prometheus.CounterVec
+	retryFailures  *prometheus.CounterVec
+	retryBackoff   *prometheus.HistogramVec
+
+	rateLimitAllowed *prometheus.CounterVec
+	rateLimitDropped *prometheus.CounterVec
+
+	asyncEnqueued    *prometheus.CounterVec
+	asyncOverflow    *prometheus.CounterVec
+	asyncQueueDepth  *prometheus.GaugeVec
+	asyncBoosters    *prometheus.GaugeVec
+	asyncSendLatency *prometheus.HistogramVec
+}
+
+// make sure the collector can receive the events of the hooks
+var _ hooks.Metrics = (*Collector)(nil)
+
+// constructor --------------------------------------------------------
+
+// collectorParams defines the options of the collector
+type collectorParams struct {
+	buckets     []float64
+	constLabels prometheus.Labels
+}
+
+// Option is a functional option to update the collector configuration
+type Option func(conf *collectorParams)
+
+// Buckets sets the buckets, in seconds, of the histograms of latency and backoff
+func Buckets(buckets ...float64) Option {
+	return func(conf *collectorParams) {
+		conf.buckets = buckets
+	}
+}
+
+// ConstLabels sets labels that are added to all metrics, like the name of the service
+func ConstLabels(labels prometheus.Labels) Option {
+	return func(conf *collectorParams) {
+		conf.constLabels = labels
+	}
+}
+
+// NewCollector creates a collector of the events of the hooks
+func NewCollector(opts ...Option) *Collector {
+
+	// default configuration
+	conf := collectorParams{
+		buckets: prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: conf.constLabels,
+		}, []string{labelStage, labelLevel})
+	}
+	gauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: conf.constLabels,
+		}, []string{labelStage})
+	}
+	histogram := func(name, help string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: conf.constLabels,
+			Buckets:     conf.buckets,
+		}, []string{labelStage, labelLevel})
+	}
+
+	return &Collector{
+		retryAttempts:  counter("retry_attempts_total", "Number of attempts to deliver a message."),
+		retrySuccesses: counter("retry_successes_total", "Number of messages delivered after one or more attempts."),
+		retryFailures:  counter("retry_failures_total", "Number of messages not delivered after all attempts."),
+		retryBackoff:   histogram("retry_backoff_seconds", "Pauses between the attempts to deliver a message."),
+
+		rateLimitAllowed: counter("ratelimit_allowed_total", "Number of messages permitted by the rate limit."),
+		rateLimitDropped: counter("ratelimit_dropped_total", "Number of messages dropped by the rate limit."),
+
+		asyncEnqueued:    counter("async_enqueued_total", "Number of messages queued up for sending."),
+		asyncOverflow:    counter("async_overflow_total", "Number of messages dropped because the buffer was full."),
+		asyncQueueDepth:  gauge("async_queue_depth", "Number of messages waiting in the buffer."),
+		asyncBoosters:    gauge("async_boosters", "Number of running extra senders."),
+		asyncSendLatency: histogram("async_send_latency_seconds", "Time it took to send out a message."),
+	}
+}
+
+// implementation -----------------------------------------------------
+
+// collectors lists all metrics of the collector
+func (c *Collector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		c.retryAttempts,
+		c.retrySuccesses,
+		c.retryFailures,
+		c.retryBackoff,
+		c.rateLimitAllowed,
+		c.rateLimitDropped,
+		c.asyncEnqueued,
+		c.asyncOverflow,
+		c.asyncQueueDepth,
+		c.asyncBoosters,
+		c.asyncSendLatency,
+	}
+}
+
+// Describe sends the descriptions of all metrics of the collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(ch)
+	}
+}
+
+// Collect sends the current values of all metrics of the collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(ch)
+	}
+}
+
+func (c *Collector) RetryAttempt(stage string, level logrus.Level) {
+	c.retryAttempts.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) RetrySuccess(stage string, level logrus.Level, _ int) {
+	c.retrySuccesses.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) RetryFailure(stage string, level logrus.Level, _ int) {
+	c.retryFailures.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) RetryBackoff(stage string, level logrus.Level, delay time.Duration) {
+	c.retryBackoff.WithLabelValues(stage, levelLabel(level)).Observe(delay.Seconds())
+}
+
+func (c *Collector) RateLimitAllow(stage string, level logrus.Level) {
+	c.rateLimitAllowed.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) RateLimitDeny(stage string, level logrus.Level) {
+	c.rateLimitDropped.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) AsyncEnqueue(stage string, level logrus.Level) {
+	c.asyncEnqueued.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) AsyncOverflow(stage string, level logrus.Level) {
+	c.asyncOverflow.WithLabelValues(stage, levelLabel(level)).Inc()
+}
+
+func (c *Collector) AsyncQueueDepth(stage string, depth int) {
+	c.asyncQueueDepth.WithLabelValues(stage).Set(float64(depth))
+}
+
+func (c *Collector) AsyncBoosters(stage string, boosters int) {
+	c.asyncBoosters.WithLabelValues(stage).Set(float64(boosters))
+}
+
+func (c *Collector) AsyncSendLatency(stage string, level logrus.Level, latency time.Duration) {
+	c.asyncSendLatency.WithLabelValues(stage, levelLabel(level)).Observe(latency.Seconds())
+}
+
+// levelLabel is the value of the level label
+func levelLabel(level logrus.Level) string {
+	if level == hooks.NoLevel {
+		return "none"
+	}
+
+	return level.String()
+}
@@ -0,0 +1,121 @@
+package promhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	hooks "logrus-hooks.git"
+)
+
+// failingHook is a simple hook that always fails
+type failingHook struct{}
+
+func (failingHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (failingHook) Fire(*logrus.Entry) error {
+	return errors.New("failing hook")
+}
+
+func TestCollector_Register(t *testing.T) {
+	registry := prometheus.NewPedanticRegistry()
+	if err := registry.Register(NewCollector()); err != nil {
+		t.Fatalf("failed to register the collector: %s", err)
+	}
+
+	if problems, err := testutil.CollectAndLint(NewCollector()); err != nil || len(problems) > 0 {
+		t.Errorf("collector has problems: err=%v, problems=%v", err, problems)
+	}
+}
+
+func TestCollector_Events(t *testing.T) {
+	collector := NewCollector()
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Level = logrus.ErrorLevel
+
+	retry := hooks.RetryHook(failingHook{}, time.Microsecond,
+		hooks.Retries(2),
+		hooks.RetryMetrics(collector, "audit-retry"),
+	)
+	if retry.Fire(entry) == nil {
+		t.Fatalf("retry hook did not fail")
+	}
+
+	limiter := hooks.RateLimitHook(retry,
+		hooks.PerSecond(1),
+		hooks.Burst(1),
+		hooks.RateLimitMetrics(collector, "audit-limits"),
+	)
+	_ = limiter.Fire(entry)
+	_ = limiter.Fire(entry)
+
+	// the messages without a level are labeled apart from the panics
+	_ = retry.Fire(nil)
+
+	testData := []struct {
+		collector prometheus.Collector
+		labels    []string
+		expected  float64
+	}{
+		{collector.retryAttempts, []string{"audit-retry", "error"}, 6},
+		{collector.retryFailures, []string{"audit-retry", "error"}, 2},
+		{collector.retryFailures, []string{"audit-retry", "none"}, 1},
+		{collector.retryFailures, []string{"audit-retry", "panic"}, 0},
+		{collector.rateLimitAllowed, []string{"audit-limits", "error"}, 1},
+		{collector.rateLimitDropped, []string{"audit-limits", "error"}, 1},
+	}
+
+	for i, td := range testData {
+		vec, ok := td.collector.(*prometheus.CounterVec)
+		if !ok {
+			t.Fatalf("unexpected type of metric at [test=%d]", i)
+		}
+		if n := testutil.ToFloat64(vec.WithLabelValues(td.labels...)); n != td.expected {
+			t.Errorf("wrong value at [test=%d]: expected=%v, found=%v", i, td.expected, n)
+		}
+	}
+
+	// one histogram for the errors and one for the messages without a level
+	if n := testutil.CollectAndCount(collector, "logrus_hooks_retry_backoff_seconds"); n != 2 {
+		t.Errorf("wrong number of backoff histograms: %d", n)
+	}
+}
+
+func TestCollector_Async(t *testing.T) {
+	collector := NewCollector(ConstLabels(prometheus.Labels{"service": "test"}))
+
+	hook := hooks.AsyncHook(failingHook{},
+		hooks.Senders(0),
+		hooks.BoostSenders(0),
+		hooks.BufferLen(2),
+		hooks.AsyncMetrics(collector, "audit-async"),
+	)
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+	for i := 0; i < 3; i++ {
+		_ = hook.Fire(logrus.NewEntry(logrus.StandardLogger()))
+	}
+
+	expected := `
+# HELP logrus_hooks_async_queue_depth Number of messages waiting in the buffer.
+# TYPE logrus_hooks_async_queue_depth gauge
+logrus_hooks_async_queue_depth{service="test",stage="audit-async"} 2
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"logrus_hooks_async_queue_depth"); err != nil {
+		t.Errorf("unexpected queue depth: %s", err)
+	}
+
+	if n := testutil.ToFloat64(collector.asyncOverflow.WithLabelValues("audit-async", "panic")); n != 1 {
+		t.Errorf("wrong number of overflows: expected=1, found=%v", n)
+	}
+}
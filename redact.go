@@ -0,0 +1,282 @@
+package hooks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultRedactMask replaces the values of the redacted fields
+const defaultRedactMask = "[REDACTED]"
+
+var (
+	// defaultRedactFields are the names of the fields that are redacted by default
+	defaultRedactFields = []string{
+		"password", "passwd", "secret", "token", "access_token", "refresh_token",
+		"api_key", "apikey", "authorization", "cookie",
+	}
+
+	// defaultRedactPatterns are the patterns that are masked by default
+	defaultRedactPatterns = []redactPattern{
+		{
+			name: "email",
+			re:   regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			name:  "card",
+			re:    regexp.MustCompile(`\b[0-9](?:[ \-]?[0-9]){12,18}\b`),
+			valid: luhnValid,
+		},
+		{
+			name: "bearer",
+			re:   regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`),
+		},
+		{
+			name: "aws_key",
+			re:   regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		},
+	}
+)
+
+// redactHook is a Logrus hook that masks secrets and personal data in the messages
+type redactHook struct {
+	ChainImpl
+	conf redactParams
+}
+
+// redactParams defines the rules of the hook
+type redactParams struct {
+	mask     string
+	salt     string
+	fields   map[string]bool
+	hashed   map[string]bool
+	patterns []redactPattern
+
+	// defaults enables the default rules
+	defaults bool
+}
+
+// redactPattern is a regular expression whose matches are masked, valid
+// filters out the matches that are not really sensitive
+type redactPattern struct {
+	name  string
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// constructor --------------------------------------------------------
+
+// RedactOption is a functional option to update the redact hook configuration
+type RedactOption func(conf *redactParams)
+
+// RedactFields sets the names of the fields whose values are replaced by the
+// mask, the names are not case sensitive
+func RedactFields(names ...string) RedactOption {
+	return func(conf *redactParams) {
+		for _, name := range names {
+			conf.fields[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// HashFields sets the names of the fields whose values are replaced by their
+// hash, so that the messages can still be correlated by these fields
+func HashFields(names ...string) RedactOption {
+	return func(conf *redactParams) {
+		for _, name := range names {
+			conf.hashed[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// HashSalt sets the secret that is added to the values before they are hashed
+func HashSalt(salt string) RedactOption {
+	return func(conf *redactParams) {
+		conf.salt = salt
+	}
+}
+
+// RedactPattern masks the matches of the regular expression in the message
+// and in the text of the fields, like "[REDACTED:name]"
+func RedactPattern(name string, re *regexp.Regexp) RedactOption {
+	return func(conf *redactParams) {
+		conf.patterns = append(conf.patterns, redactPattern{name: name, re: re})
+	}
+}
+
+// RedactMask sets the text that replaces the values of the redacted fields
+func RedactMask(mask string) RedactOption {
+	return func(conf *redactParams) {
+		conf.mask = mask
+	}
+}
+
+// NoDefaultRedactRules disables the default rules of the hook
+func NoDefaultRedactRules() RedactOption {
+	return func(conf *redactParams) {
+		conf.defaults = false
+	}
+}
+
+// RedactHook creates a Logrus hook that masks secrets and personal data in the
+// messages before they are sent to the next hook
+//
+// The default rules redact common secret fields, like password and token, and
+// mask emails, credit card numbers, bearer tokens and AWS access keys. The hook
+// works on a copy of the log entry, the original entry is not changed.
+func RedactHook(next logrus.Hook, opts ...RedactOption) logrus.Hook {
+
+	hook := &redactHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: redactParams{
+			mask:     defaultRedactMask,
+			fields:   make(map[string]bool),
+			hashed:   make(map[string]bool),
+			defaults: true,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	if hook.conf.defaults {
+		for _, name := range defaultRedactFields {
+			if !hook.conf.hashed[name] {
+				hook.conf.fields[name] = true
+			}
+		}
+		hook.conf.patterns = append(hook.conf.patterns, defaultRedactPatterns...)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends a redacted copy of the message to the next hook
+func (h *redactHook) Fire(entry *logrus.Entry) error {
+	if entry == nil {
+		return h.next.Fire(entry)
+	}
+
+	return h.next.Fire(h.conf.redact(entry))
+}
+
+// Inspect describes the rules of the hook
+func (h *redactHook) Inspect() map[string]interface{} {
+	patterns := make([]string, 0, len(h.conf.patterns))
+	for _, p := range h.conf.patterns {
+		patterns = append(patterns, p.name)
+	}
+
+	return map[string]interface{}{
+		"fields":   sortedKeys(h.conf.fields),
+		"hashed":   sortedKeys(h.conf.hashed),
+		"patterns": patterns,
+	}
+}
+
+// redact makes a copy of the log entry with the rules applied to it
+func (conf *redactParams) redact(entry *logrus.Entry) *logrus.Entry {
+	redacted := copyEntry(entry)
+	redacted.Message = conf.maskPatterns(entry.Message)
+
+	for k, v := range redacted.Data {
+		name := strings.ToLower(k)
+		switch {
+		case conf.hashed[name]:
+			redacted.Data[k] = conf.hash(v)
+		case conf.fields[name]:
+			redacted.Data[k] = conf.mask
+		default:
+			redacted.Data[k] = conf.maskValue(v)
+		}
+	}
+
+	return redacted
+}
+
+// maskValue replaces the matches of the patterns in the text of a value, the
+// values of other types and the values without matches are kept as they are
+func (conf *redactParams) maskValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		return conf.maskPatterns(value)
+	case []byte:
+		if masked, changed := conf.maskText(string(value)); changed {
+			return []byte(masked)
+		}
+	case error:
+		if masked, changed := conf.maskText(value.Error()); changed {
+			return errors.New(masked)
+		}
+	case fmt.Stringer:
+		if masked, changed := conf.maskText(value.String()); changed {
+			return masked
+		}
+	}
+
+	return v
+}
+
+// maskText masks the patterns in the text and reports whether any were found
+func (conf *redactParams) maskText(s string) (string, bool) {
+	masked := conf.maskPatterns(s)
+
+	return masked, masked != s
+}
+
+// maskPatterns replaces the matches of the patterns in the text
+func (conf *redactParams) maskPatterns(s string) string {
+	for _, p := range conf.patterns {
+		s = p.re.ReplaceAllStringFunc(s, func(match string) string {
+			if p.valid != nil && !p.valid(match) {
+				return match
+			}
+			return "[REDACTED:" + p.name + "]"
+		})
+	}
+
+	return s
+}
+
+// hash replaces a value with its salted hash
+func (conf *redactParams) hash(v interface{}) string {
+	sum := sha256.Sum256([]byte(conf.salt + fmt.Sprint(v)))
+
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// luhnValid checks the digits of a credit card number
+func luhnValid(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+
+	return n >= 13 && sum%10 == 0
+}
@@ -11,7 +11,8 @@ package hooks
 // https://github.com/paypal/gorealis/blob/master/retry.go
 
 import (
-	"fmt"
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -39,10 +40,19 @@ type backoff struct {
 
 	// maxRetries is the maximum number of retries
 	maxRetries int
+
+	// metrics receives the events of the hook under the name of the stage
+	metrics Metrics
+	stage   string
+
+	// clock is the source of time of the pauses between retries
+	clock Clock
 }
 
 // retryHook is a Logrus hook that that will try to log a message multiple times
 type retryHook struct {
+	sync.RWMutex
+
 	ChainImpl
 	backoff
 }
@@ -52,6 +62,15 @@ type retryHook struct {
 // RetryOption is a functional option to update the retry hook configuration
 type RetryOption func(conf *backoff)
 
+// RetryDelay sets the base delay between retries
+func RetryDelay(d time.Duration) RetryOption {
+	return func(conf *backoff) {
+		if d >= 0 {
+			conf.retryDelay = d
+		}
+	}
+}
+
 // FactorPct sets the increase (in percents of the delay) that will be added to the delay after each retry
 func FactorPct(n int64) RetryOption {
 	return func(conf *backoff) {
@@ -75,7 +94,26 @@ func Retries(n int) RetryOption {
 	}
 }
 
+// RetryMetrics sets the metrics that receive the events of the hook
+func RetryMetrics(m Metrics, stage string) RetryOption {
+	return func(conf *backoff) {
+		conf.metrics = m
+		conf.stage = stage
+	}
+}
+
+// RetryClock sets the clock that the pauses between retries use
+func RetryClock(c Clock) RetryOption {
+	return func(conf *backoff) {
+		conf.clock = c
+	}
+}
+
 // RetryHook creates a Logrus hook that will try to log a message multiple times
+//
+// The messages that fail all attempts return *RetryExhaustedError. The
+// messages that are dropped with ErrNotRunning are not retried, and the error
+// is returned as it is.
 func RetryHook(next logrus.Hook, delay time.Duration, opts ...RetryOption) logrus.Hook {
 
 	hook := &retryHook{
@@ -105,31 +143,85 @@ func RetryHook(next logrus.Hook, delay time.Duration, opts ...RetryOption) logru
 // Fire makes multiple attempts to deliver the message to the next hook
 func (h *retryHook) Fire(entry *logrus.Entry) error {
 
-	delay := h.retryDelay
+	// the configuration may change while the message is being retried
+	h.RLock()
+	conf := h.backoff
+	h.RUnlock()
+
+	clock := clockOrSystem(conf.clock)
+	start, delay := clock.Now(), conf.retryDelay
+	metrics, level := observe(conf.metrics), entryLevel(entry)
 
 	var err error
-	for retries := 0; retries <= h.maxRetries; retries++ {
+	for retries := 0; retries <= conf.maxRetries; retries++ {
+		metrics.RetryAttempt(conf.stage, level)
 		if err = h.next.Fire(entry); err == nil {
 			// message logged successfully
+			metrics.RetrySuccess(conf.stage, level, retries+1)
 			return nil
 		}
-		if retries == h.maxRetries {
-			// maximum number of retries reached
+		traceEvent(entry, "logrus.hook.retry.failure",
+			Attribute{Key: "logrus_hooks.attempt", Value: retries + 1},
+			Attribute{Key: "logrus_hooks.error", Value: err.Error()},
+		)
+		if errors.Is(err, ErrNotRunning) {
+			// the next hook drops the messages until it is started, the
+			// message is not retried and the error is not wrapped
+			metrics.RetryFailure(conf.stage, level, retries+1)
 			return err
 		}
+		if retries == conf.maxRetries {
+			// maximum number of retries reached
+			metrics.RetryFailure(conf.stage, level, retries+1)
+			break
+		}
 
 		adjustedDelay := delay
 		if retries > 0 {
-			adjustedDelay += makeJitter(delay, h.jitterPct)
-			delay += incrDelay(delay, h.factorPct)
+			adjustedDelay += makeJitter(delay, conf.jitterPct)
+			delay += incrDelay(delay, conf.factorPct)
 		}
 
 		// pause between reties
-		time.Sleep(adjustedDelay)
+		metrics.RetryBackoff(conf.stage, level, adjustedDelay)
+		traceEvent(entry, "logrus.hook.retry.backoff",
+			Attribute{Key: "logrus_hooks.delay_ms", Value: adjustedDelay.Milliseconds()},
+		)
+		clock.Sleep(adjustedDelay)
 	}
 
 	// all retries failed
-	return fmt.Errorf("failed after [%d] retries: %w", h.maxRetries, err)
+	return &RetryExhaustedError{
+		Attempts: conf.maxRetries + 1,
+		Elapsed:  clock.Now().Sub(start),
+		Last:     err,
+	}
+}
+
+// Reconfigure changes the backoff, the messages that are being retried keep
+// the backoff they started with
+func (h *retryHook) Reconfigure(opts ...RetryOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	for _, opt := range opts {
+		opt(&h.backoff)
+	}
+
+	return nil
+}
+
+// Inspect describes the backoff of the hook
+func (h *retryHook) Inspect() map[string]interface{} {
+	h.RLock()
+	defer h.RUnlock()
+
+	return map[string]interface{}{
+		"delay":      h.retryDelay.String(),
+		"retries":    h.maxRetries,
+		"factor_pct": h.factorPct,
+		"jitter_pct": h.jitterPct,
+	}
 }
 
 func incrDelay(delay time.Duration, factorPct int64) time.Duration {
@@ -64,36 +64,12 @@ func TestMakeJitter(t *testing.T) {
 	}
 }
 
-func TestRetryFailure(t *testing.T) {
+func TestRetryNotRunning(t *testing.T) {
+	async := AsyncHook(&mockCannedHook{})
+	hook := RetryHook(async, time.Hour, Retries(3))
 
-	nTests := 8
-
-	for i := 0; i < nTests; i++ {
-		hook := RetryHook(
-			&mockRetryHook{maxFailures: i + 1},
-			time.Microsecond,
-			Retries(i),
-		)
-
-		if err := hook.Fire(nil); err == nil {
-			t.Errorf("success with %d retries", i)
-		}
-	}
-}
-
-func TestRetrySuccess(t *testing.T) {
-
-	nTests := 8
-
-	for i := 0; i < nTests; i++ {
-		hook := RetryHook(
-			&mockRetryHook{maxFailures: i},
-			time.Microsecond,
-			Retries(i),
-		)
-
-		if err := hook.Fire(nil); err != nil {
-			t.Errorf("failed with %d retries", i)
-		}
+	// the message is dropped by the stopped hook without pauses between retries
+	if err := hook.Fire(nil); err != ErrNotRunning {
+		t.Errorf("unexpected error of a hook that is not running: %v", err)
 	}
 }
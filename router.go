@@ -0,0 +1,311 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Matcher is a condition on a log entry that selects the route of the entry,
+// any function of this type can be used as a custom condition
+type Matcher func(entry *logrus.Entry) bool
+
+// routerHook is a Logrus hook that sends each message to the hooks of the
+// routes that match it
+type routerHook struct {
+	sync.Mutex
+
+	conf    routerParams
+	running bool
+}
+
+// routerParams defines the routes of the hook
+type routerParams struct {
+	routes       []*route
+	defaultRoute *route
+	matchAll     bool
+}
+
+// route is a hook with the conditions that the messages sent to it must meet
+type route struct {
+	name     string
+	hook     logrus.Hook
+	matchers []Matcher
+
+	// matched counts the messages sent to the route
+	matched atomic.Uint64
+}
+
+// constructor --------------------------------------------------------
+
+// RouterOption is a functional option to update the router hook configuration
+type RouterOption func(conf *routerParams)
+
+// Route adds a route that receives the messages that meet all conditions,
+// the routes are evaluated in the order in which they are added
+//
+// The route is skipped for the messages at the levels its hook does not fire.
+//
+// The hook of the route can be a chain of hooks or a pipeline, which are
+// started and stopped together with the router.
+func Route(name string, hook logrus.Hook, matchers ...Matcher) RouterOption {
+	return func(conf *routerParams) {
+		conf.routes = append(conf.routes, &route{
+			name:     name,
+			hook:     hook,
+			matchers: matchers,
+		})
+	}
+}
+
+// DefaultRoute sets the hook that receives the messages that no route matches
+func DefaultRoute(hook logrus.Hook) RouterOption {
+	return func(conf *routerParams) {
+		conf.defaultRoute = &route{
+			name: "default",
+			hook: hook,
+		}
+	}
+}
+
+// MatchAll sends the messages to all routes that match them, instead of
+// only to the first one
+func MatchAll() RouterOption {
+	return func(conf *routerParams) {
+		conf.matchAll = true
+	}
+}
+
+// RouterHook creates a Logrus hook that sends each message to the hooks of the
+// routes that match it
+//
+// Messages that match no route are sent to the default route, if there is one,
+// and they are dropped otherwise.
+func RouterHook(opts ...RouterOption) logrus.Hook {
+
+	hook := &routerHook{}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	return hook
+}
+
+// AtLevel matches messages of the given log levels
+func AtLevel(levels ...logrus.Level) Matcher {
+	return func(entry *logrus.Entry) bool {
+		for _, level := range levels {
+			if entry.Level == level {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FieldEquals matches messages that have a field with the given value
+func FieldEquals(key string, value interface{}) Matcher {
+	return func(entry *logrus.Entry) bool {
+		v, found := entry.Data[key]
+		return found && reflect.DeepEqual(v, value)
+	}
+}
+
+// FieldPresent matches messages that have the field
+func FieldPresent(key string) Matcher {
+	return func(entry *logrus.Entry) bool {
+		_, found := entry.Data[key]
+		return found
+	}
+}
+
+// MessageMatches matches messages whose text matches the regular expression
+func MessageMatches(re *regexp.Regexp) Matcher {
+	return func(entry *logrus.Entry) bool {
+		return re.MatchString(entry.Message)
+	}
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends the message to the hooks of the routes that match it
+func (h *routerHook) Fire(entry *logrus.Entry) error {
+	var errs []error
+	for _, r := range h.conf.routes {
+		if !r.matches(entry) {
+			continue
+		}
+
+		errs = append(errs, r.fire(entry))
+		if !h.conf.matchAll {
+			return errors.Join(errs...)
+		}
+	}
+
+	if len(errs) == 0 && h.conf.defaultRoute != nil && h.conf.defaultRoute.acceptsLevel(entry) {
+		return h.conf.defaultRoute.fire(entry)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Levels are the logging levels of all routes
+func (h *routerHook) Levels() []logrus.Level {
+	seen := make(map[logrus.Level]bool)
+	levels := make([]logrus.Level, 0, len(logrus.AllLevels))
+	for _, r := range h.allRoutes() {
+		for _, level := range r.hook.Levels() {
+			if !seen[level] {
+				seen[level] = true
+				levels = append(levels, level)
+			}
+		}
+	}
+
+	return levels
+}
+
+// IsRunning queries the state of the hook
+func (h *routerHook) IsRunning() bool {
+	h.Lock()
+	defer h.Unlock()
+
+	return h.running
+}
+
+// Start starts the hooks of the routes that can be started
+//
+// When a route fails to start, the routes that were started before it are
+// stopped again and the router is left stopped.
+func (h *routerHook) Start() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.running {
+		return nil
+	}
+
+	routes := h.allRoutes()
+	for i, r := range routes {
+		if hook, ok := r.hook.(RunningHook); ok {
+			if err := hook.Start(); err != nil {
+				return errors.Join(
+					fmt.Errorf("failed to start route %q: %w", r.name, err),
+					stopRoutes(routes[:i]),
+				)
+			}
+		}
+	}
+
+	h.running = true
+
+	return nil
+}
+
+// Stop stops the hooks of the routes that can be stopped
+func (h *routerHook) Stop() error {
+	h.Lock()
+	defer h.Unlock()
+
+	if !h.running {
+		return nil
+	}
+
+	err := stopRoutes(h.allRoutes())
+	h.running = false
+
+	return err
+}
+
+// stopRoutes stops the hooks of the routes that can be stopped
+func stopRoutes(routes []*route) error {
+	var errs []error
+	for _, r := range routes {
+		if hook, ok := r.hook.(RunningHook); ok {
+			errs = append(errs, hook.Stop())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Flush makes the hooks of the routes send out the messages they are holding back
+func (h *routerHook) Flush() error {
+	var errs []error
+	for _, r := range h.allRoutes() {
+		if hook, ok := r.hook.(Flusher); ok {
+			errs = append(errs, hook.Flush())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Inspect describes the routes and the number of messages sent to each one
+func (h *routerHook) Inspect() map[string]interface{} {
+	routes := make([]interface{}, 0, len(h.conf.routes)+1)
+	for _, r := range h.allRoutes() {
+		routes = append(routes, map[string]interface{}{
+			"name":    r.name,
+			"matched": r.matched.Load(),
+		})
+	}
+
+	return map[string]interface{}{
+		"routes":    routes,
+		"match_all": h.conf.matchAll,
+	}
+}
+
+// allRoutes lists the routes followed by the default route
+func (h *routerHook) allRoutes() []*route {
+	if h.conf.defaultRoute == nil {
+		return h.conf.routes
+	}
+
+	return append(h.conf.routes[:len(h.conf.routes):len(h.conf.routes)], h.conf.defaultRoute)
+}
+
+// matches checks the message against all conditions of the route
+func (r *route) matches(entry *logrus.Entry) bool {
+	if entry == nil || !r.acceptsLevel(entry) {
+		return false
+	}
+
+	for _, match := range r.matchers {
+		if !match(entry) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// acceptsLevel checks that the hook of the route fires at the level of the
+// message, the messages without a level are accepted
+func (r *route) acceptsLevel(entry *logrus.Entry) bool {
+	if entry == nil {
+		return true
+	}
+
+	for _, level := range r.hook.Levels() {
+		if entry.Level == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fire sends the message to the hook of the route
+func (r *route) fire(entry *logrus.Entry) error {
+	r.matched.Add(1)
+
+	return r.hook.Fire(entry)
+}
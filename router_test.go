@@ -0,0 +1,229 @@
+package hooks
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMatchers(t *testing.T) {
+	entry := newTestEntry(logrus.WarnLevel, "user login failed", logrus.Fields{
+		"audit": true,
+		"user":  "alice",
+	})
+
+	testData := []struct {
+		name     string
+		matcher  Matcher
+		expected bool
+	}{
+		{"level", AtLevel(logrus.ErrorLevel, logrus.WarnLevel), true},
+		{"other level", AtLevel(logrus.InfoLevel), false},
+		{"field equals", FieldEquals("user", "alice"), true},
+		{"field differs", FieldEquals("user", "bob"), false},
+		{"field bool", FieldEquals("audit", true), true},
+		{"field present", FieldPresent("audit"), true},
+		{"field missing", FieldPresent("tenant"), false},
+		{"message", MessageMatches(regexp.MustCompile(`login (failed|denied)`)), true},
+		{"other message", MessageMatches(regexp.MustCompile(`^logout`)), false},
+		{"custom", func(e *logrus.Entry) bool { return len(e.Data) == 2 }, true},
+	}
+
+	for _, td := range testData {
+		if matched := td.matcher(entry); matched != td.expected {
+			t.Errorf("matcher %q: expected=%v, found=%v", td.name, td.expected, matched)
+		}
+	}
+}
+
+func TestRouter_FirstMatch(t *testing.T) {
+	var audit, errs, others mockRecordingHook
+
+	hook := RouterHook(
+		Route("audit", &audit, FieldEquals("audit", true)),
+		Route("errors", &errs, AtLevel(logrus.ErrorLevel)),
+		DefaultRoute(&others),
+	)
+
+	auditEntry := newTestEntry(logrus.ErrorLevel, "audit message", logrus.Fields{"audit": true})
+	errorEntry := newTestEntry(logrus.ErrorLevel, "error message", nil)
+	infoEntry := newTestEntry(logrus.InfoLevel, "info message", nil)
+
+	for _, entry := range []*logrus.Entry{auditEntry, errorEntry, infoEntry} {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	audit.compare(t, []*logrus.Entry{auditEntry})
+	errs.compare(t, []*logrus.Entry{errorEntry})
+	others.compare(t, []*logrus.Entry{infoEntry})
+}
+
+func TestRouter_MatchAll(t *testing.T) {
+	var audit, errs, others mockRecordingHook
+
+	hook := RouterHook(
+		Route("audit", &audit, FieldEquals("audit", true)),
+		Route("errors", &errs, AtLevel(logrus.ErrorLevel)),
+		DefaultRoute(&others),
+		MatchAll(),
+	)
+
+	auditEntry := newTestEntry(logrus.ErrorLevel, "audit message", logrus.Fields{"audit": true})
+	infoEntry := newTestEntry(logrus.InfoLevel, "info message", nil)
+
+	for _, entry := range []*logrus.Entry{auditEntry, infoEntry} {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	audit.compare(t, []*logrus.Entry{auditEntry})
+	errs.compare(t, []*logrus.Entry{auditEntry})
+	others.compare(t, []*logrus.Entry{infoEntry})
+
+	state := hook.(Inspector).Inspect()
+	routes := state["routes"].([]interface{})
+	if len(routes) != 3 {
+		t.Fatalf("wrong number of routes: %v", routes)
+	}
+	for i, expected := range []uint64{1, 1, 1} {
+		if matched := routes[i].(map[string]interface{})["matched"]; matched != expected {
+			t.Errorf("wrong number of messages of route [%d]: expected=%d, found=%v", i, expected, matched)
+		}
+	}
+}
+
+func TestRouter_NoDefault(t *testing.T) {
+	failure := errors.New("delivery failed")
+	hook := RouterHook(
+		Route("errors", &mockCannedHook{levels: logrus.AllLevels, fireResult: failure}, AtLevel(logrus.ErrorLevel)),
+	)
+
+	if err := hook.Fire(newTestEntry(logrus.InfoLevel, "info message", nil)); err != nil {
+		t.Errorf("message without a route failed: %s", err)
+	}
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("nil message failed: %s", err)
+	}
+	if err := hook.Fire(newTestEntry(logrus.ErrorLevel, "error message", nil)); !errors.Is(err, failure) {
+		t.Errorf("unexpected error of the route: %v", err)
+	}
+}
+
+func TestRouter_HookLevels(t *testing.T) {
+	var errs, others mockRecordingHook
+	errorsOnly := &mockCannedHook{levels: []logrus.Level{logrus.ErrorLevel}}
+
+	hook := RouterHook(
+		Route("errors", errorsOnly, FieldPresent("alert")),
+		Route("alerts", &errs, FieldPresent("alert")),
+		DefaultRoute(&others),
+	)
+
+	// the first route does not fire at the debug level, the next one does
+	debug := newTestEntry(logrus.DebugLevel, "debug alert", logrus.Fields{"alert": true})
+	if err := hook.Fire(debug); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+	errs.compare(t, []*logrus.Entry{debug})
+
+	// the default route is skipped at the levels of its hook too
+	hook = RouterHook(DefaultRoute(errorsOnly))
+	errorsOnly.fireResult = errors.New("wrong level")
+	if err := hook.Fire(newTestEntry(logrus.InfoLevel, "info message", nil)); err != nil {
+		t.Errorf("default route fired at a level of the hook: %s", err)
+	}
+	others.compare(t, nil)
+}
+
+func TestRouter_Levels(t *testing.T) {
+	hook := RouterHook(
+		Route("errors", &mockCannedHook{levels: []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel}}),
+		DefaultRoute(&mockCannedHook{levels: []logrus.Level{logrus.WarnLevel, logrus.InfoLevel}}),
+	)
+
+	levels := hook.Levels()
+	expected := []logrus.Level{logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel}
+	if len(levels) != len(expected) {
+		t.Fatalf("wrong levels: expected=%v, found=%v", expected, levels)
+	}
+	for i := range levels {
+		if levels[i] != expected[i] {
+			t.Errorf("wrong levels: expected=%v, found=%v", expected, levels)
+		}
+	}
+}
+
+func TestRouter_Pipelines(t *testing.T) {
+	var durable, syslog mockRecordingHook
+
+	durableAsync := AsyncHook(&durable)
+	durablePipeline := NewPipeline("durable",
+		Stage{Name: "retry", Type: "retry", Hook: RetryHook(durableAsync, 0)},
+		Stage{Name: "async", Type: "async", Hook: durableAsync},
+		Stage{Name: "sink", Type: "sink", Hook: &durable},
+	)
+
+	router := RouterHook(
+		Route("security", durablePipeline, FieldPresent("security")),
+		DefaultRoute(&syslog),
+	)
+	pipeline := NewPipeline("router",
+		Stage{Name: "router", Type: "router", Hook: router},
+	)
+
+	if err := pipeline.Start(); err != nil {
+		t.Fatalf("failed to start the pipeline: %s", err)
+	}
+	if !durablePipeline.IsRunning() || !durableAsync.IsRunning() {
+		t.Fatalf("pipeline of the route was not started")
+	}
+
+	security := newTestEntry(logrus.WarnLevel, "access denied", logrus.Fields{"security": "acl"})
+	other := newTestEntry(logrus.InfoLevel, "request served", nil)
+	for _, entry := range []*logrus.Entry{security, other} {
+		if err := pipeline.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+	}
+
+	if err := pipeline.Stop(); err != nil {
+		t.Fatalf("failed to stop the pipeline: %s", err)
+	}
+	if durablePipeline.IsRunning() || durableAsync.IsRunning() {
+		t.Fatalf("pipeline of the route was not stopped")
+	}
+
+	durable.compare(t, []*logrus.Entry{security})
+	syslog.compare(t, []*logrus.Entry{other})
+}
+
+func TestRouter_StartStop(t *testing.T) {
+	async := AsyncHook(&mockCannedHook{})
+	router := RouterHook(
+		Route("async", async, FieldPresent("async")),
+		DefaultRoute(&mockCannedHook{levels: logrus.AllLevels}),
+	).(RunningHook)
+
+	// stopping a router that was never started does nothing
+	if err := router.Stop(); err != nil {
+		t.Fatalf("stop before the start failed: %s", err)
+	}
+
+	startErr := errors.New("route failed to start")
+	failing := RouterHook(
+		Route("async", async, FieldPresent("async")),
+		DefaultRoute(&mockRunningHook{startResult: startErr}),
+	).(RunningHook)
+
+	if err := failing.Start(); !errors.Is(err, startErr) {
+		t.Fatalf("unexpected result of the failed start: %v", err)
+	}
+	if failing.IsRunning() || async.IsRunning() {
+		t.Errorf("routes are running after the failed start")
+	}
+}
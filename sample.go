@@ -0,0 +1,291 @@
+package hooks
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// default sampling interval and number of tracked messages
+	defaultSampleTick = time.Second
+	defaultSampleKeys = 4096
+)
+
+// samplingHook is a Logrus hook that sends out a sample of the messages
+type samplingHook struct {
+	sync.Mutex
+
+	ChainImpl
+	conf sampleParams
+
+	// counters holds the number of times each message was seen in the
+	// current interval
+	counters *keyCache[*sampleCounter]
+}
+
+// sampleParams defines the sampling policies of the hook
+type sampleParams struct {
+
+	// counting enables the policy to send the first messages of every interval
+	// and every Mth message after that
+	counting   bool
+	tick       time.Duration
+	first      int
+	thereafter int
+	maxKeys    int
+
+	// rate is the fraction of messages that are sent out, levels holds the
+	// rates of the log levels that do not use the default rate
+	rate   float64
+	levels map[logrus.Level]float64
+
+	// field is the name of the field whose value decides whether the message
+	// is sent out, all messages with the same value are sampled together
+	field string
+
+	// clock is the source of time of the intervals
+	clock Clock
+}
+
+// sampleCounter is the number of times a message was seen in an interval
+type sampleCounter struct {
+	start time.Time
+	count int
+}
+
+// constructor --------------------------------------------------------
+
+// SampleOption is a functional option to update the sampling hook configuration
+type SampleOption func(conf *sampleParams)
+
+// Tick sets the interval in which the first messages are sent out
+func Tick(d time.Duration) SampleOption {
+	return func(conf *sampleParams) {
+		if d > 0 {
+			conf.tick = d
+		}
+	}
+}
+
+// First sets the number of times the same message is sent out in every interval
+func First(n int) SampleOption {
+	return func(conf *sampleParams) {
+		if n >= 0 {
+			conf.counting = true
+			conf.first = n
+		}
+	}
+}
+
+// Thereafter sets that every Mth message is sent out after the first messages
+// of the interval, zero drops all of them
+func Thereafter(m int) SampleOption {
+	return func(conf *sampleParams) {
+		if m >= 0 {
+			conf.counting = true
+			conf.thereafter = m
+		}
+	}
+}
+
+// SampleRate sets the fraction, between 0 and 1, of the messages that are sent
+// out, the rate applies to the given log levels or to all other levels when
+// no levels are given
+func SampleRate(rate float64, levels ...logrus.Level) SampleOption {
+	return func(conf *sampleParams) {
+		rate = math.Max(0, math.Min(1, rate))
+		if len(levels) == 0 {
+			conf.rate = rate
+			return
+		}
+
+		if conf.levels == nil {
+			conf.levels = make(map[logrus.Level]float64)
+		}
+		for _, level := range levels {
+			conf.levels[level] = rate
+		}
+	}
+}
+
+// SampleByField makes the sample rate decision depend on the value of a field
+//
+// The messages with the same value of the field, like a trace ID, are either
+// all sent out or all dropped. Messages without the field are sampled at random.
+func SampleByField(name string) SampleOption {
+	return func(conf *sampleParams) {
+		conf.field = name
+	}
+}
+
+// SampleClock sets the clock that drives the intervals of the hook
+func SampleClock(c Clock) SampleOption {
+	return func(conf *sampleParams) {
+		conf.clock = c
+	}
+}
+
+// SamplingHook creates a Logrus hook that sends out a sample of the messages
+//
+// Two policies can be combined. The first one sends out the first N times
+// a message is seen in every interval and then every Mth time after that.
+// The second one sends out a fraction of the messages, either at random or
+// by the value of a field. Dropped messages are not reported as errors.
+func SamplingHook(next logrus.Hook, opts ...SampleOption) logrus.Hook {
+
+	hook := &samplingHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: sampleParams{
+			tick:    defaultSampleTick,
+			maxKeys: defaultSampleKeys,
+			rate:    1,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	hook.counters = newKeyCache[*sampleCounter](hook.conf.maxKeys, hook.conf.tick)
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends the message to the next hook if it is part of the sample
+func (h *samplingHook) Fire(entry *logrus.Entry) error {
+
+	h.Lock()
+	conf := h.conf
+	h.Unlock()
+
+	if conf.counting && !h.count(entry, clockOrSystem(conf.clock).Now()) {
+		return nil
+	}
+	if !conf.sample(entry) {
+		return nil
+	}
+
+	return h.next.Fire(entry)
+}
+
+// Reconfigure changes the sampling policies, the counts of the messages
+// start over in a new interval
+func (h *samplingHook) Reconfigure(opts ...SampleOption) error {
+	h.Lock()
+	defer h.Unlock()
+
+	conf := h.conf
+	conf.levels = make(map[logrus.Level]float64, len(h.conf.levels))
+	for level, rate := range h.conf.levels {
+		conf.levels[level] = rate
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+
+	h.conf = conf
+	h.counters = newKeyCache[*sampleCounter](conf.maxKeys, conf.tick)
+
+	return nil
+}
+
+// Inspect describes the sampling policies of the hook
+func (h *samplingHook) Inspect() map[string]interface{} {
+	h.Lock()
+	defer h.Unlock()
+
+	levelRates := map[string]float64{}
+	for level, rate := range h.conf.levels {
+		levelRates[level.String()] = rate
+	}
+
+	return map[string]interface{}{
+		"counting":    h.conf.counting,
+		"tick":        h.conf.tick.String(),
+		"first":       h.conf.first,
+		"thereafter":  h.conf.thereafter,
+		"rate":        h.conf.rate,
+		"level_rates": levelRates,
+		"by_field":    h.conf.field,
+		"messages":    h.counters.len(),
+	}
+}
+
+// count applies the policy of the first N and every Mth message
+func (h *samplingHook) count(entry *logrus.Entry, now time.Time) bool {
+	key := ""
+	if entry != nil {
+		key = fmt.Sprintf("%d|%s", entry.Level, entry.Message)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.counters.expire(now)
+	counter, found := h.counters.peek(key)
+	if !found || now.Sub(counter.start) >= h.conf.tick {
+		counter = &sampleCounter{start: now}
+		h.counters.add(key, counter, now)
+	}
+
+	counter.count++
+	if counter.count <= h.conf.first {
+		return true
+	}
+
+	return h.conf.thereafter > 0 && (counter.count-h.conf.first)%h.conf.thereafter == 0
+}
+
+// sample applies the policy of the fraction of messages
+func (conf *sampleParams) sample(entry *logrus.Entry) bool {
+	rate := conf.rate
+	if entry != nil {
+		if r, found := conf.levels[entry.Level]; found {
+			rate = r
+		}
+	}
+
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	}
+
+	if conf.field != "" && entry != nil {
+		if value, found := entry.Data[conf.field]; found {
+			return hashFraction(fmt.Sprint(value)) < rate
+		}
+	}
+
+	return rand.Float64() < rate
+}
+
+// hashFraction maps a string to a number in [0, 1) that is evenly distributed
+func hashFraction(s string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	// mix the bits, FNV alone is not evenly distributed for similar strings
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	return float64(x>>11) / (1 << 53)
+}
@@ -0,0 +1,154 @@
+package hooks
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mockCountingHook is a simple hook that counts the messages it receives
+type mockCountingHook struct {
+	ChainImpl
+	count int64
+}
+
+func (mock *mockCountingHook) Fire(entry *logrus.Entry) error {
+	atomic.AddInt64(&mock.count, 1)
+	return nil
+}
+
+func TestSampling_FirstThereafter(t *testing.T) {
+	testData := []struct {
+		first, thereafter, messages, expected int
+	}{
+		{1, 0, 100, 1},
+		{10, 0, 100, 10},
+		{10, 10, 100, 19},
+		{0, 5, 100, 20},
+		{100, 10, 100, 100},
+	}
+
+	for _, td := range testData {
+		var mockHook mockCountingHook
+		hook := SamplingHook(&mockHook, First(td.first), Thereafter(td.thereafter))
+
+		t.Run(fmt.Sprintf("first=%d,thereafter=%d", td.first, td.thereafter), func(t *testing.T) {
+			for i := 0; i < td.messages; i++ {
+				for _, message := range []string{"message a", "message b"} {
+					entry := logrus.NewEntry(logrus.StandardLogger())
+					entry.Message = message
+					if err := hook.Fire(entry); err != nil {
+						t.Fatalf("fire failed at round [%d]: %s", i, err)
+					}
+				}
+			}
+
+			// each message is counted separately
+			if n := int(mockHook.count); n != 2*td.expected {
+				t.Errorf("wrong number of sent messages: expected=%d, found=%d", 2*td.expected, n)
+			}
+		})
+	}
+}
+
+func TestSampling_Rate(t *testing.T) {
+	testData := []float64{
+		0, 0.1, 0.5, 0.9, 1,
+	}
+
+	nMessages := 10000
+	for _, td := range testData {
+		var mockHook mockCountingHook
+		hook := SamplingHook(&mockHook, SampleRate(td), SampleRate(1, logrus.ErrorLevel))
+
+		t.Run(fmt.Sprintf("rate=%.1f", td), func(t *testing.T) {
+			entry := logrus.NewEntry(logrus.StandardLogger())
+			entry.Level = logrus.InfoLevel
+			for i := 0; i < nMessages; i++ {
+				if err := hook.Fire(entry); err != nil {
+					t.Fatalf("fire failed at round [%d]: %s", i, err)
+				}
+			}
+
+			expected := td * float64(nMessages)
+			if n := float64(mockHook.count); n < expected-0.05*float64(nMessages) || n > expected+0.05*float64(nMessages) {
+				t.Errorf("number of sent messages is out of range: expected=%.0f, found=%.0f", expected, n)
+			}
+
+			// errors are always sent out
+			mockHook.count = 0
+			entry.Level = logrus.ErrorLevel
+			for i := 0; i < 100; i++ {
+				if err := hook.Fire(entry); err != nil {
+					t.Fatalf("fire failed at round [%d]: %s", i, err)
+				}
+			}
+			if n := mockHook.count; n != 100 {
+				t.Errorf("error messages were sampled: %d out of 100 sent", n)
+			}
+		})
+	}
+}
+
+func TestSampling_ByField(t *testing.T) {
+	var mockHook mockCountingHook
+	hook := SamplingHook(&mockHook, SampleRate(0.5), SampleByField("trace_id"))
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		traceID := fmt.Sprintf("trace-%d", i)
+		entry := logrus.NewEntry(logrus.StandardLogger()).WithField("trace_id", traceID)
+
+		// all messages of a trace share the same fate
+		before := mockHook.count
+		for j := 0; j < 10; j++ {
+			if err := hook.Fire(entry); err != nil {
+				t.Fatalf("fire failed at round [%d]: %s", i, err)
+			}
+		}
+
+		switch mockHook.count - before {
+		case 0:
+		case 10:
+			kept++
+		default:
+			t.Errorf("messages of trace %s were split: %d out of 10 sent", traceID, mockHook.count-before)
+		}
+	}
+
+	if kept < 25 || kept > 75 {
+		t.Errorf("number of kept traces is out of range: %d out of 100", kept)
+	}
+}
+
+func TestSampling_Reconfigure(t *testing.T) {
+	var mockHook mockCountingHook
+	hook := SamplingHook(&mockHook)
+
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	for i := 0; i < 10; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed at round [%d]: %s", i, err)
+		}
+	}
+
+	theHook, ok := hook.(Reconfigurable[SampleOption])
+	if !ok {
+		t.Fatalf("sampling hook can not be reconfigured: %v", hook)
+	}
+	if err := theHook.Reconfigure(First(5)); err != nil {
+		t.Fatalf("failed to reconfigure the sampling hook: %s", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed at round [%d]: %s", i, err)
+		}
+	}
+
+	if n := mockHook.count; n != 15 {
+		t.Errorf("wrong number of sent messages: expected=15, found=%d", n)
+	}
+}
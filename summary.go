@@ -0,0 +1,107 @@
+package hooks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// suppression counts the log messages that were dropped by a hook
+type suppression struct {
+	sync.Mutex
+
+	// total is the number of dropped messages
+	total int
+
+	// levels is the number of dropped messages per log level
+	levels map[logrus.Level]int
+
+	// since is the time when the first message was dropped
+	since time.Time
+}
+
+// add counts one more dropped message
+func (s *suppression) add(entry *logrus.Entry, now time.Time) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.total == 0 {
+		s.since = now
+		s.levels = make(map[logrus.Level]int)
+	}
+
+	s.total++
+	if entry != nil {
+		s.levels[entry.Level]++
+	}
+}
+
+// summary creates a log entry that reports the dropped messages and starts
+// a new count, nil is returned when no messages were dropped
+func (s *suppression) summary(entry *logrus.Entry, now time.Time) *logrus.Entry {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.total == 0 {
+		return nil
+	}
+
+	// the summary has the most severe level of the dropped messages
+	level := logrus.TraceLevel
+	counts := make([]string, 0, len(s.levels))
+	for i := len(logrus.AllLevels) - 1; i >= 0; i-- {
+		l := logrus.AllLevels[i]
+		if n := s.levels[l]; n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", n, l))
+			level = l
+		}
+	}
+	if len(counts) == 0 {
+		level = logrus.WarnLevel
+	}
+
+	message := fmt.Sprintf("suppressed %d entries", s.total)
+	if len(counts) > 0 {
+		message += " (" + strings.Join(counts, ", ") + ")"
+	}
+	message += fmt.Sprintf(" in the last %s", roundDuration(now.Sub(s.since)))
+
+	summary := syntheticEntry(entry, level, message, now)
+	summary.Data["suppressed"] = s.total
+
+	s.total = 0
+	s.levels = nil
+
+	return summary
+}
+
+// syntheticEntry creates a log entry that is generated by a hook on behalf
+// of the entry that is being processed
+func syntheticEntry(entry *logrus.Entry, level logrus.Level, message string, now time.Time) *logrus.Entry {
+	logger := logrus.StandardLogger()
+	if entry != nil && entry.Logger != nil {
+		logger = entry.Logger
+	}
+
+	synthetic := logrus.NewEntry(logger)
+	synthetic.Level = level
+	synthetic.Message = message
+	synthetic.Time = now
+	if entry != nil {
+		synthetic.Context = entry.Context
+	}
+
+	return synthetic
+}
+
+// roundDuration makes durations easier to read in messages
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+
+	return d.Round(time.Second)
+}
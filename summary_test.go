@@ -0,0 +1,46 @@
+package hooks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSuppression_Summary(t *testing.T) {
+	var s suppression
+
+	now := time.Now()
+	if summary := s.summary(nil, now); summary != nil {
+		t.Fatalf("summary was created without suppressed messages: %s", summary.Message)
+	}
+
+	for i, level := range []logrus.Level{
+		logrus.WarnLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.DebugLevel,
+	} {
+		entry := logrus.NewEntry(logrus.StandardLogger())
+		entry.Level = level
+		s.add(entry, now.Add(time.Duration(i)*time.Second))
+	}
+
+	summary := s.summary(nil, now.Add(10*time.Second))
+	if summary == nil {
+		t.Fatalf("summary was not created")
+	}
+
+	expected := "suppressed 4 entries (1 debug, 2 warning, 1 error) in the last 10s"
+	if summary.Message != expected {
+		t.Errorf("wrong summary message: expected=%q, found=%q", expected, summary.Message)
+	}
+	if summary.Level != logrus.ErrorLevel {
+		t.Errorf("wrong summary level: expected=%s, found=%s", logrus.ErrorLevel, summary.Level)
+	}
+	if n := summary.Data["suppressed"]; n != 4 {
+		t.Errorf("wrong number of suppressed messages: expected=4, found=%v", n)
+	}
+
+	// the count starts over after the summary
+	if summary := s.summary(nil, now); summary != nil {
+		t.Errorf("summary was created twice: %s", summary.Message)
+	}
+}
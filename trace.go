@@ -0,0 +1,89 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Span is a span of the trace of a log entry that covers its delivery
+//
+// The hooks record their work as events of the span in the context of the
+// log entry, a tracing hook puts the span there with ContextWithSpan. The
+// otelhooks package has such a hook that traces with OpenTelemetry.
+type Span interface {
+	// AddEvent records an event of a hook with its attributes
+	AddEvent(name string, attrs ...Attribute)
+
+	// Start starts a child span, the returned context carries the child
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+
+	// End ends the span, the error marks the work of the span as failed
+	End(err error)
+}
+
+// Attribute is a named value that describes an event or a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// spanKey is the key of the span in the context of a log entry
+type spanKey struct{}
+
+// asyncSpanKey is the key of the span of the async hook, which ends when
+// the message is sent out by a sender
+type asyncSpanKey struct{}
+
+// ContextWithSpan makes a copy of the context that carries the span
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext finds the span in the context, nil when there is none
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// entryContext is the context of a log entry, which may be missing
+func entryContext(entry *logrus.Entry) context.Context {
+	if entry == nil || entry.Context == nil {
+		return context.Background()
+	}
+
+	return entry.Context
+}
+
+// traceEvent adds an event to the span in the context of the log entry, if
+// there is one
+func traceEvent(entry *logrus.Entry, name string, attrs ...Attribute) {
+	if span := SpanFromContext(entryContext(entry)); span != nil {
+		span.AddEvent(name, attrs...)
+	}
+}
+
+// startAsyncSpan starts the span of the async hook when the message is queued
+// up, the returned entry carries the span to the sender
+func startAsyncSpan(entry *logrus.Entry, stage string) *logrus.Entry {
+	parent := SpanFromContext(entryContext(entry))
+	if parent == nil {
+		return entry
+	}
+
+	ctx, span := parent.Start(entryContext(entry), "logrus.hook.async",
+		Attribute{Key: "logrus_hooks.stage", Value: stage},
+	)
+
+	queued := copyEntry(entry)
+	queued.Context = context.WithValue(ctx, asyncSpanKey{}, span)
+
+	return queued
+}
+
+// endAsyncSpan ends the span of the async hook after the message was sent out
+func endAsyncSpan(entry *logrus.Entry, err error) {
+	if span, ok := entryContext(entry).Value(asyncSpanKey{}).(Span); ok {
+		span.End(err)
+	}
+}
@@ -0,0 +1,149 @@
+package hooks
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// mockSpan is a span that keeps the names of its events and its children
+type mockSpan struct {
+	sync.Mutex
+	name     string
+	attrs    []Attribute
+	events   []string
+	children []*mockSpan
+	ended    bool
+	err      error
+}
+
+func (mock *mockSpan) AddEvent(name string, attrs ...Attribute) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	mock.events = append(mock.events, name)
+}
+
+func (mock *mockSpan) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	child := &mockSpan{name: name, attrs: attrs}
+	mock.children = append(mock.children, child)
+
+	return ContextWithSpan(ctx, child), child
+}
+
+func (mock *mockSpan) End(err error) {
+	mock.Lock()
+	defer mock.Unlock()
+
+	mock.ended = true
+	mock.err = err
+}
+
+// count is the number of events with the given name
+func (mock *mockSpan) count(name string) int {
+	mock.Lock()
+	defer mock.Unlock()
+
+	n := 0
+	for _, event := range mock.events {
+		if event == name {
+			n++
+		}
+	}
+
+	return n
+}
+
+func TestTrace_Events(t *testing.T) {
+	span := &mockSpan{}
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ContextWithSpan(context.Background(), span))
+
+	retry := RetryHook(&mockRetryHook{maxFailures: 2}, 0, Retries(3))
+	if err := retry.Fire(entry); err != nil {
+		t.Fatalf("failed with 3 retries: %s", err)
+	}
+	if n := span.count("logrus.hook.retry.failure"); n != 2 {
+		t.Errorf("wrong number of failure events: expected=2, found=%d", n)
+	}
+	if n := span.count("logrus.hook.retry.backoff"); n != 2 {
+		t.Errorf("wrong number of backoff events: expected=2, found=%d", n)
+	}
+
+	limit := RateLimitHook(&mockCannedHook{}, PerSecond(1), Burst(1))
+	for i := 0; i < 2; i++ {
+		_ = limit.Fire(entry)
+	}
+	if span.count("logrus.hook.ratelimit.allow") != 1 || span.count("logrus.hook.ratelimit.deny") != 1 {
+		t.Errorf("wrong events of the rate limit: %v", span.events)
+	}
+}
+
+func TestTrace_Async(t *testing.T) {
+	span := &mockSpan{}
+	sinkErr := errors.New("sink is down")
+	hook := AsyncHook(&mockCannedHook{fireResult: sinkErr}, AsyncMetrics(nil, "async"), AsyncErrorHandler(func(error) {}))
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ContextWithSpan(context.Background(), span))
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	// the child span covers the wait in the queue and the send
+	if len(span.children) != 1 {
+		t.Fatalf("wrong number of child spans: expected=1, found=%d", len(span.children))
+	}
+	child := span.children[0]
+	if child.name != "logrus.hook.async" || len(child.attrs) != 1 || child.attrs[0].Value != "async" {
+		t.Errorf("wrong child span: %s %v", child.name, child.attrs)
+	}
+	if child.count("logrus.hook.async.dequeue") != 1 {
+		t.Errorf("child span has no dequeue event: %v", child.events)
+	}
+	if !child.ended || child.err != sinkErr {
+		t.Errorf("child span was not ended with the error of the send: ended=%t, err=%v", child.ended, child.err)
+	}
+	if entry.Context.Value(asyncSpanKey{}) != nil {
+		t.Errorf("context of the original entry was changed")
+	}
+}
+
+func TestTrace_NotTraced(t *testing.T) {
+	var mockHook mockRecordingHook
+	hook := AsyncHook(&mockHook)
+
+	if err := hook.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	// messages without a span are queued up as they are
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = "test message"
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if err := hook.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	mockHook.messages.Range(func(_, value interface{}) bool {
+		if ctx := value.(*logrus.Entry).Context; ctx != nil && SpanFromContext(ctx) != nil {
+			t.Errorf("message without a span was traced")
+		}
+		return true
+	})
+}
@@ -0,0 +1,97 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContextExtractor reads fields from the context of a log entry
+type ContextExtractor func(ctx context.Context) logrus.Fields
+
+// traceFieldsHook is a Logrus hook that adds the values in the context of the
+// messages, like the identifiers of their trace, to their fields
+type traceFieldsHook struct {
+	ChainImpl
+	conf traceFieldsParams
+}
+
+// traceFieldsParams defines the fields added by the hook
+type traceFieldsParams struct {
+	extractors []ContextExtractor
+}
+
+// constructor --------------------------------------------------------
+
+// TraceFieldsOption is a functional option to update the trace fields hook configuration
+type TraceFieldsOption func(conf *traceFieldsParams)
+
+// Extractor adds an extractor of fields from the context of the messages
+func Extractor(fn ContextExtractor) TraceFieldsOption {
+	return func(conf *traceFieldsParams) {
+		conf.extractors = append(conf.extractors, fn)
+	}
+}
+
+// ContextValue adds the value of a context key to the field with the given name
+func ContextValue(key interface{}, field string) TraceFieldsOption {
+	return Extractor(func(ctx context.Context) logrus.Fields {
+		if value := ctx.Value(key); value != nil {
+			return logrus.Fields{field: value}
+		}
+		return nil
+	})
+}
+
+// TraceFieldsHook creates a Logrus hook that adds the fields of the extractors
+// to a copy of the message, otelhooks.SpanContext adds the trace_id, span_id
+// and trace_flags of the OpenTelemetry span in the context of the message
+//
+// Fields that are already in the message are kept. The fields are added before
+// the message is passed on, so the retries and the async sends of the next
+// hooks carry them too.
+func TraceFieldsHook(next logrus.Hook, opts ...TraceFieldsOption) logrus.Hook {
+
+	hook := &traceFieldsHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends a copy of the message with the fields of its context to the next hook
+func (h *traceFieldsHook) Fire(entry *logrus.Entry) error {
+	if entry == nil || entry.Context == nil {
+		return h.next.Fire(entry)
+	}
+
+	var traced *logrus.Entry
+	for _, extract := range h.conf.extractors {
+		for k, v := range extract(entry.Context) {
+			if _, found := entry.Data[k]; found {
+				continue
+			}
+			if traced == nil {
+				traced = copyEntry(entry)
+			}
+			traced.Data[k] = v
+		}
+	}
+
+	if traced == nil {
+		// nothing to add, the message does not need a copy
+		return h.next.Fire(entry)
+	}
+
+	return h.next.Fire(traced)
+}
@@ -0,0 +1,130 @@
+package hooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// context keys of the values in the tests
+type (
+	requestIDKey struct{}
+	spanIDKey    struct{}
+)
+
+// newTracedEntry creates a log entry whose context has a span id and a request id
+func newTracedEntry(msg string) *logrus.Entry {
+	ctx := context.WithValue(context.Background(), spanIDKey{}, "00f067aa0ba902b7")
+	ctx = context.WithValue(ctx, requestIDKey{}, "req-42")
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithContext(ctx)
+	entry.Message = msg
+
+	return entry
+}
+
+var expectedTraceFields = logrus.Fields{
+	"span_id":    "00f067aa0ba902b7",
+	"request_id": "req-42",
+}
+
+func TestTraceFields_Fire(t *testing.T) {
+	var mockHook mockLastHook
+	hook := TraceFieldsHook(&mockHook,
+		ContextValue(spanIDKey{}, "span_id"),
+		ContextValue(requestIDKey{}, "request_id"),
+	)
+
+	entry := newTracedEntry("traced message").WithField("span_id", "caller")
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	data := mockHook.entry.Data
+	for k, v := range expectedTraceFields {
+		if k == "span_id" {
+			v = "caller"
+		}
+		if data[k] != v {
+			t.Errorf("wrong value of field %q: expected=%v, found=%v", k, v, data[k])
+		}
+	}
+
+	if len(entry.Data) != 1 {
+		t.Errorf("original entry was changed: %v", entry.Data)
+	}
+}
+
+func TestTraceFields_NoTrace(t *testing.T) {
+	var mockHook mockLastHook
+	hook := TraceFieldsHook(&mockHook, ContextValue(requestIDKey{}, "request_id"))
+
+	// without a span and context values the message is passed on as it is
+	entries := []*logrus.Entry{
+		logrus.NewEntry(logrus.StandardLogger()),
+		logrus.NewEntry(logrus.StandardLogger()).WithContext(context.Background()),
+	}
+	for _, entry := range entries {
+		if err := hook.Fire(entry); err != nil {
+			t.Fatalf("fire failed: %s", err)
+		}
+		if mockHook.entry != entry {
+			t.Errorf("message without a trace was copied")
+		}
+	}
+
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("fire failed: %s", err)
+	}
+}
+
+func TestTraceFields_RetryAsync(t *testing.T) {
+	var mockHook mockRecordingHook
+
+	async := AsyncHook(RetryHook(&flakyRecordingHook{ChainImpl: ChainImpl{ChainElement{next: &mockHook}}}, 0))
+	hook := TraceFieldsHook(async,
+		ContextValue(spanIDKey{}, "span_id"),
+		Extractor(func(ctx context.Context) logrus.Fields {
+			return logrus.Fields{"request_id": ctx.Value(requestIDKey{})}
+		}),
+	)
+
+	if err := async.Start(); err != nil {
+		t.Fatalf("failed to start the async hook: %s", err)
+	}
+
+	entry := newTracedEntry("traced message")
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	if err := async.Stop(); err != nil {
+		t.Fatalf("failed to stop the async hook: %s", err)
+	}
+
+	received, found := mockHook.messages.Load(entry.Message)
+	if !found {
+		t.Fatalf("message was not received")
+	}
+	for k, v := range expectedTraceFields {
+		if data := received.(*logrus.Entry).Data; data[k] != v {
+			t.Errorf("wrong value of field %q: expected=%v, found=%v", k, v, data[k])
+		}
+	}
+}
+
+// flakyRecordingHook fails the first time it is called and then relays the messages
+type flakyRecordingHook struct {
+	ChainImpl
+	failed bool
+}
+
+func (mock *flakyRecordingHook) Fire(entry *logrus.Entry) error {
+	if !mock.failed {
+		mock.failed = true
+		return ErrBufferFull
+	}
+
+	return mock.next.Fire(entry)
+}
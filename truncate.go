@@ -0,0 +1,259 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultTruncateSize is the default limit of the encoded size of a message
+	defaultTruncateSize = 64 * 1024
+
+	// defaultTruncateMarker is the name of the field that lists what was truncated
+	defaultTruncateMarker = "truncated"
+)
+
+// truncateHook is a Logrus hook that trims messages that are too large for the next hook
+type truncateHook struct {
+	ChainImpl
+	conf truncateParams
+}
+
+// truncateParams defines the size limits of the hook, zero means no limit
+type truncateParams struct {
+	maxMessage  int
+	maxFieldLen int
+	maxFields   int
+	maxSize     int
+	marker      string
+}
+
+// constructor --------------------------------------------------------
+
+// TruncateOption is a functional option to update the truncate hook configuration
+type TruncateOption func(conf *truncateParams)
+
+// MaxMessage sets the maximum length of the message text in bytes
+func MaxMessage(n int) TruncateOption {
+	return func(conf *truncateParams) {
+		if n >= 0 {
+			conf.maxMessage = n
+		}
+	}
+}
+
+// MaxFieldLen sets the maximum length of the string values of the fields in bytes
+func MaxFieldLen(n int) TruncateOption {
+	return func(conf *truncateParams) {
+		if n >= 0 {
+			conf.maxFieldLen = n
+		}
+	}
+}
+
+// MaxFields sets the maximum number of fields, the fields are kept in the
+// order of their names
+func MaxFields(n int) TruncateOption {
+	return func(conf *truncateParams) {
+		if n >= 0 {
+			conf.maxFields = n
+		}
+	}
+}
+
+// MaxSize sets the maximum size of the message text and the fields encoded as JSON
+func MaxSize(n int) TruncateOption {
+	return func(conf *truncateParams) {
+		if n >= 0 {
+			conf.maxSize = n
+		}
+	}
+}
+
+// TruncateMarker sets the name of the field that lists what was truncated
+func TruncateMarker(name string) TruncateOption {
+	return func(conf *truncateParams) {
+		conf.marker = name
+	}
+}
+
+// TruncateHook creates a Logrus hook that trims the messages that exceed the
+// size limits, so that they are not rejected by the next hook
+//
+// The trimmed message gets a marker field that lists what was truncated,
+// like ["message", "fields", "field:payload", "size"]. The fields that are too
+// large for the total size are dropped, the largest ones first, and then the
+// message text is trimmed. The hook works on a copy of the log entry.
+func TruncateHook(next logrus.Hook, opts ...TruncateOption) logrus.Hook {
+
+	hook := &truncateHook{
+		ChainImpl: ChainImpl{
+			ChainElement{
+				next: next,
+			},
+		},
+		// default configuration
+		conf: truncateParams{
+			maxSize: defaultTruncateSize,
+			marker:  defaultTruncateMarker,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&hook.conf)
+	}
+
+	return hook
+}
+
+// implementation -----------------------------------------------------
+
+// Fire sends the message to the next hook, trimmed if it exceeds the limits
+func (h *truncateHook) Fire(entry *logrus.Entry) error {
+	if entry == nil {
+		return h.next.Fire(entry)
+	}
+
+	return h.next.Fire(h.conf.truncate(entry))
+}
+
+// Inspect describes the size limits of the hook
+func (h *truncateHook) Inspect() map[string]interface{} {
+	return map[string]interface{}{
+		"max_message":   h.conf.maxMessage,
+		"max_field_len": h.conf.maxFieldLen,
+		"max_fields":    h.conf.maxFields,
+		"max_size":      h.conf.maxSize,
+	}
+}
+
+// truncate returns the log entry if it is within the limits, or a trimmed copy
+func (conf *truncateParams) truncate(entry *logrus.Entry) *logrus.Entry {
+	var (
+		trimmed *logrus.Entry
+		marks   []string
+	)
+	mark := func(what string) {
+		if trimmed == nil {
+			trimmed = copyEntry(entry)
+		}
+		marks = append(marks, what)
+	}
+
+	if conf.maxMessage > 0 && len(entry.Message) > conf.maxMessage {
+		mark("message")
+		trimmed.Message = trimString(entry.Message, conf.maxMessage)
+	}
+
+	keys := sortedKeys(entry.Data)
+	if conf.maxFields > 0 && len(keys) > conf.maxFields {
+		mark("fields")
+		for _, k := range keys[conf.maxFields:] {
+			delete(trimmed.Data, k)
+		}
+		keys = keys[:conf.maxFields]
+	}
+
+	if conf.maxFieldLen > 0 {
+		for _, k := range keys {
+			if s, ok := entry.Data[k].(string); ok && len(s) > conf.maxFieldLen {
+				mark("field:" + k)
+				trimmed.Data[k] = trimString(s, conf.maxFieldLen)
+			}
+		}
+	}
+
+	current := entry
+	if trimmed != nil {
+		current = trimmed
+	}
+	if conf.maxSize > 0 && encodedSize(current) > conf.maxSize {
+		mark("size")
+		// the marker field has to fit in too
+		fitSize(trimmed, conf.maxSize-len(conf.marker)-valueSize(marks))
+	}
+
+	if trimmed == nil {
+		return entry
+	}
+
+	trimmed.Data[conf.marker] = marks
+
+	return trimmed
+}
+
+// fitSize drops the largest fields and then trims the message text until the
+// log entry fits in the maximum size
+func fitSize(entry *logrus.Entry, maxSize int) {
+	sizes := make(map[string]int, len(entry.Data))
+	keys := make([]string, 0, len(entry.Data))
+	total := len(entry.Message)
+	for k, v := range entry.Data {
+		sizes[k] = len(k) + valueSize(v)
+		keys = append(keys, k)
+		total += sizes[k]
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, k := range keys {
+		if total <= maxSize {
+			return
+		}
+		delete(entry.Data, k)
+		total -= sizes[k]
+	}
+
+	if total > maxSize {
+		entry.Message = trimString(entry.Message, len(entry.Message)-(total-maxSize))
+	}
+}
+
+// encodedSize is the size of the message text and the fields of a log entry
+func encodedSize(entry *logrus.Entry) int {
+	size := len(entry.Message)
+	for k, v := range entry.Data {
+		size += len(k) + valueSize(v)
+	}
+
+	return size
+}
+
+// valueSize is the size of a field value encoded as JSON
+func valueSize(v interface{}) int {
+	if err, ok := v.(error); ok {
+		// logrus formatters write the text of the errors
+		return len(err.Error()) + 2
+	}
+
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return len(fmt.Sprint(v))
+	}
+
+	return len(encoded)
+}
+
+// trimString cuts the text to at most n bytes, without splitting a character
+func trimString(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
@@ -0,0 +1,165 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestTrimString(t *testing.T) {
+	testData := []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"hello", 0, ""},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+	}
+
+	for _, td := range testData {
+		if s := trimString(td.s, td.n); s != td.expected {
+			t.Errorf("trim %q to %d: expected=%q, found=%q", td.s, td.n, td.expected, s)
+		}
+	}
+}
+
+func TestTruncate_Limits(t *testing.T) {
+	var mockHook mockLastHook
+	hook := TruncateHook(&mockHook,
+		MaxMessage(10),
+		MaxFieldLen(4),
+		MaxFields(2),
+	)
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		"a": "long value",
+		"b": 12345678,
+		"c": "dropped",
+	})
+	entry.Message = "a message that is too long"
+
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	received := mockHook.entry
+	if received.Message != "a message " {
+		t.Errorf("message was not truncated: %q", received.Message)
+	}
+	if received.Data["a"] != "long" || received.Data["b"] != 12345678 {
+		t.Errorf("fields were not truncated: %v", received.Data)
+	}
+	if _, found := received.Data["c"]; found {
+		t.Errorf("extra field was not dropped: %v", received.Data)
+	}
+
+	marks := fmt.Sprint(received.Data[defaultTruncateMarker])
+	if marks != "[message fields field:a]" {
+		t.Errorf("wrong truncation marker: %s", marks)
+	}
+
+	// the original entry is not changed
+	if len(entry.Data) != 3 || entry.Message != "a message that is too long" {
+		t.Errorf("original entry was changed: %q %v", entry.Message, entry.Data)
+	}
+}
+
+func TestTruncate_Size(t *testing.T) {
+	var mockHook mockLastHook
+	hook := TruncateHook(&mockHook, MaxSize(100), TruncateMarker("trimmed"))
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithFields(logrus.Fields{
+		"payload": strings.Repeat("x", 200),
+		"user":    "alice",
+		"err":     errors.New("boom"),
+	})
+	entry.Message = "request failed"
+
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+
+	received := mockHook.entry
+	if size := encodedSize(received); size > 100 {
+		t.Errorf("message is still too large: %d", size)
+	}
+	if _, found := received.Data["payload"]; found {
+		t.Errorf("largest field was not dropped")
+	}
+	if received.Data["user"] != "alice" || received.Message != "request failed" {
+		t.Errorf("small fields were changed: %q %v", received.Message, received.Data)
+	}
+	if marks := fmt.Sprint(received.Data["trimmed"]); marks != "[size]" {
+		t.Errorf("wrong truncation marker: %s", marks)
+	}
+
+	// the message text is trimmed when there are no more fields to drop
+	entry.Message = strings.Repeat("y", 200)
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+	if size := encodedSize(mockHook.entry); size > 100 {
+		t.Errorf("message is still too large: %d", size)
+	}
+	if len(mockHook.entry.Message) == 0 {
+		t.Errorf("message text was dropped")
+	}
+}
+
+func TestTruncate_WithinLimits(t *testing.T) {
+	var mockHook mockLastHook
+	hook := TruncateHook(&mockHook, MaxMessage(100), MaxFieldLen(100), MaxFields(10))
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField("user", "alice")
+	entry.Message = "short message"
+
+	if err := hook.Fire(entry); err != nil {
+		t.Fatalf("fire failed: %s", err)
+	}
+	if mockHook.entry != entry {
+		t.Errorf("message within the limits was copied")
+	}
+
+	if err := hook.Fire(nil); err != nil {
+		t.Errorf("fire failed: %s", err)
+	}
+}
+
+func TestTruncate_Retry(t *testing.T) {
+	// the sink rejects large messages, which can not succeed on retry
+	sink := &mockSizeHook{maxSize: 50}
+	hook := TruncateHook(RetryHook(sink, 0, Retries(2)), MaxSize(50))
+
+	entry := logrus.NewEntry(logrus.StandardLogger()).WithField("payload", strings.Repeat("x", 100))
+	entry.Message = "large message"
+
+	if err := hook.Fire(entry); err != nil {
+		t.Errorf("truncated message was rejected: %s", err)
+	}
+	if sink.calls != 1 {
+		t.Errorf("truncated message was retried: %d", sink.calls)
+	}
+}
+
+// mockSizeHook rejects the messages that are larger than the limit
+type mockSizeHook struct {
+	ChainImpl
+	maxSize int
+	calls   int
+}
+
+func (mock *mockSizeHook) Fire(entry *logrus.Entry) error {
+	mock.calls++
+	if size := encodedSize(entry); size > mock.maxSize {
+		return fmt.Errorf("message of size %d is too large", size)
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@
 // - retry transmission with exponential backoff and jitter
 // - rate limits on the number of logging messages
 // - asynchronous execution
+// - sampling and suppression of duplicate messages
 package hooks
 
 import "github.com/sirupsen/logrus"
@@ -31,3 +32,31 @@ type RunningHook interface {
 	// Stop transitions the hook to a state in which it does not send messages
 	Stop() error
 }
+
+// Flusher is a Logrus hook that holds back messages or reports about them
+type Flusher interface {
+	logrus.Hook
+
+	// Flush sends out everything the hook is holding back
+	Flush() error
+}
+
+// Reconfigurable is a Logrus hook whose configuration can be changed while it
+// is in use, the type parameter is the functional option type of the hook
+//
+// For example, the hook created by RetryHook is a Reconfigurable[RetryOption].
+type Reconfigurable[O any] interface {
+	logrus.Hook
+
+	// Reconfigure applies the options on top of the current configuration
+	Reconfigure(opts ...O) error
+}
+
+// Inspector is a Logrus hook that can report its configuration and state
+type Inspector interface {
+	logrus.Hook
+
+	// Inspect describes the configuration and the current state of the hook,
+	// the result must be safe to encode as JSON
+	Inspect() map[string]interface{}
+}